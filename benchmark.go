@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const defaultBenchmarkFrames = 16
+
+// resolutionBucket 是基准测试和调优档案使用的分辨率档位
+type resolutionBucket struct {
+	Name          string
+	Width, Height int
+}
+
+// 按像素数从小到大排列，视频归入第一个不小于其像素数的档位
+var resolutionBuckets = []resolutionBucket{
+	{"720p", 1280, 720},
+	{"1080p", 1920, 1080},
+	{"1440p", 2560, 1440},
+	{"4k", 3840, 2160},
+}
+
+func bucketForResolution(width, height int) resolutionBucket {
+	for _, bucket := range resolutionBuckets {
+		if width*height <= bucket.Width*bucket.Height {
+			return bucket
+		}
+	}
+	return resolutionBuckets[len(resolutionBuckets)-1]
+}
+
+func findResolutionBucket(name string) (resolutionBucket, bool) {
+	for _, bucket := range resolutionBuckets {
+		if strings.EqualFold(bucket.Name, name) {
+			return bucket, true
+		}
+	}
+	return resolutionBucket{}, false
+}
+
+func resolutionBucketNames() []string {
+	var names []string
+	for _, bucket := range resolutionBuckets {
+		names = append(names, bucket.Name)
+	}
+	return names
+}
+
+// TuningProfile 是基准测试得出的某个引擎在某个分辨率档位下的最佳参数
+type TuningProfile struct {
+	Engine     string    `json:"engine"`
+	Bucket     string    `json:"bucket"`
+	Threads    string    `json:"threads"`
+	TileSize   int       `json:"tile_size,omitempty"`
+	FPS        float64   `json:"fps"` // 每秒输出帧数
+	MeasuredAt time.Time `json:"measured_at"`
+}
+
+func (p TuningProfile) String() string {
+	return benchmarkResult{Bucket: p.Bucket, Threads: p.Threads, TileSize: p.TileSize, FPS: p.FPS}.String()
+}
+
+// tuningProfile 查找引擎在该分辨率下的调优档案
+func (c *Config) tuningProfile(engine string, width, height int) (TuningProfile, bool) {
+	bucket := bucketForResolution(width, height)
+	for _, profile := range c.TuningProfiles {
+		if profile.Engine == engine && profile.Bucket == bucket.Name {
+			return profile, true
+		}
+	}
+	return TuningProfile{}, false
+}
+
+// saveTuningProfile 保存调优档案，同一引擎和档位只保留最新结果
+func (c *Config) saveTuningProfile(profile TuningProfile) {
+	c.TuningProfiles = slices.DeleteFunc(c.TuningProfiles, func(p TuningProfile) bool {
+		return p.Engine == profile.Engine && p.Bucket == profile.Bucket
+	})
+	c.TuningProfiles = append(c.TuningProfiles, profile)
+}
+
+// jobTuning 返回任务实际使用的插帧参数
+// 设置中手动指定的值优先，其次是基准测试档案，最后按分辨率自动计算
+func (c *Config) jobTuning(engine string, width, height int) effectiveTuning {
+	tuning := c.EngineTuning
+	profile, ok := c.tuningProfile(engine, width, height)
+	fromProfile := ok && isAutoValue(tuning.Threads)
+	if fromProfile {
+		tuning.Threads = profile.Threads
+	}
+	if ok && tuning.TileSize == 0 {
+		tuning.TileSize = profile.TileSize
+	}
+	if !engineHasTileSize(engine) {
+		// 设置、预设或档案中的分块大小对不支持 -t 的程序无效，不传也不写入日志
+		tuning.TileSize = 0
+	}
+	effective := tuning.resolve(width, height, engineAutoThreads(engine))
+	effective.Profile = fromProfile
+	return effective
+}
+
+// benchmarkOptions 是一次基准测试的设置
+type benchmarkOptions struct {
+	Buckets []resolutionBucket
+	Clip    string // 用户提供的视频片段，为空时使用 ffmpeg 生成的测试画面
+	Frames  int    // 每个档位使用的输入帧数
+	Tiles   []int  // 要测试的分块大小，0 表示自动
+}
+
+// benchmarkResult 是单次测试的结果
+type benchmarkResult struct {
+	Bucket   string
+	Threads  string
+	TileSize int
+	FPS      float64
+	Error    string
+}
+
+func (r benchmarkResult) String() string {
+	tile := "auto"
+	if r.TileSize > 0 {
+		tile = fmt.Sprintf("%d", r.TileSize)
+	}
+	if r.Error != "" {
+		return fmt.Sprintf("%-6s -j %-9s -t %-4s 失败: %s", r.Bucket, r.Threads, tile, r.Error)
+	}
+	return fmt.Sprintf("%-6s -j %-9s -t %-4s %.2f 帧/秒", r.Bucket, r.Threads, tile, r.FPS)
+}
+
+// benchmarkThreadCandidates 返回要测试的线程组合：自动策略的结果和几种常见比例
+func benchmarkThreadCandidates(bucket resolutionBucket) []string {
+	load, proc, save := rifeThreads(bucket.Width, bucket.Height)
+	candidates := []string{fmt.Sprintf("%d:%d:%d", load, proc, save)}
+	budget := cpuThreadBudget(bucket.Width, bucket.Height)
+	for _, n := range []int{1, 2, 4, 8} {
+		if n > budget {
+			break
+		}
+		for _, ratio := range []int{2, 4} {
+			candidate := fmt.Sprintf("%d:%d:%d", n, n*ratio, n)
+			if !slices.Contains(candidates, candidate) {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates
+}
+
+// benchmarkRuns 返回所有档位的测试次数，用于显示进度
+func benchmarkRuns(opts benchmarkOptions) int {
+	total := 0
+	for _, bucket := range opts.Buckets {
+		total += len(benchmarkThreadCandidates(bucket)) * len(opts.Tiles)
+	}
+	return total
+}
+
+// runBenchmark 在每个分辨率档位下用不同的线程和分块组合运行插帧程序，测量每秒输出帧数
+// 返回每个档位中最快且成功的组合；progress 在每次测试完成后调用
+func runBenchmark(ctx context.Context, depCheck *DependencyCheck, opts benchmarkOptions, progress func(done, total int, result benchmarkResult)) ([]TuningProfile, []benchmarkResult, error) {
+	if depCheck.Backend != nil {
+		return nil, nil, fmt.Errorf("基准测试需要可用的 AI 插帧程序: %s", depCheck.Fallback)
+	}
+	if opts.Frames < 2 {
+		opts.Frames = defaultBenchmarkFrames
+	}
+	if len(opts.Tiles) == 0 {
+		opts.Tiles = []int{0}
+	}
+
+	workRoot, err := getWorkRoot()
+	if err != nil {
+		return nil, nil, err
+	}
+	workDir, err := createWorkDir(workRoot, "benchmark")
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建工作目录失败: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	total, done := benchmarkRuns(opts), 0
+	var profiles []TuningProfile
+	var results []benchmarkResult
+	for _, bucket := range opts.Buckets {
+		inDir := filepath.Join(workDir, "in", bucket.Name)
+		if err := generateBenchmarkFrames(ctx, depCheck.Paths.FFmpeg, opts, bucket, inDir); err != nil {
+			return profiles, results, fmt.Errorf("生成 %s 测试帧失败: %w", bucket.Name, err)
+		}
+		// 用户片段可能比要求的帧数短
+		frames, err := countFiles(inDir)
+		if err != nil || frames < 2 {
+			return profiles, results, fmt.Errorf("%s 测试帧不足 2 帧", bucket.Name)
+		}
+
+		var best benchmarkResult
+		for _, threads := range benchmarkThreadCandidates(bucket) {
+			for _, tile := range opts.Tiles {
+				result := benchmarkOnce(ctx, depCheck, bucket, inDir, filepath.Join(workDir, "out"), frames, EngineTuning{Threads: threads, TileSize: tile})
+				if ctx.Err() != nil {
+					return profiles, results, ctx.Err()
+				}
+				results = append(results, result)
+				done++
+				if progress != nil {
+					progress(done, total, result)
+				}
+				if result.Error == "" && result.FPS > best.FPS {
+					best = result
+				}
+			}
+		}
+		os.RemoveAll(inDir)
+
+		if best.FPS > 0 {
+			profiles = append(profiles, TuningProfile{
+				Engine:     depCheck.Engine.Name,
+				Bucket:     bucket.Name,
+				Threads:    best.Threads,
+				TileSize:   best.TileSize,
+				FPS:        best.FPS,
+				MeasuredAt: time.Now(),
+			})
+		}
+	}
+	return profiles, results, nil
+}
+
+// generateBenchmarkFrames 把用户片段缩放到档位分辨率，或用 testsrc2 生成带运动的测试画面
+func generateBenchmarkFrames(ctx context.Context, ffmpeg string, opts benchmarkOptions, bucket resolutionBucket, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	size := fmt.Sprintf("%dx%d", bucket.Width, bucket.Height)
+	args := []string{"-y"}
+	if opts.Clip != "" {
+		args = append(args, "-i", opts.Clip, "-vf", fmt.Sprintf("scale=%d:%d", bucket.Width, bucket.Height))
+	} else {
+		args = append(args, "-f", "lavfi", "-i", "testsrc2=size="+size+":rate=30")
+	}
+	args = append(args, "-frames:v", fmt.Sprintf("%d", opts.Frames))
+	return runCommand(ctx, ffmpeg, append(args, frameFormat{Name: "png"}.Pattern(dir)))
+}
+
+// benchmarkOnce 运行一次插帧并计时，输出帧数不对也算失败
+func benchmarkOnce(ctx context.Context, depCheck *DependencyCheck, bucket resolutionBucket, inDir, outDir string, frames int, tuning EngineTuning) benchmarkResult {
+	result := benchmarkResult{Bucket: bucket.Name, Threads: tuning.Threads, TileSize: tuning.TileSize}
+	os.RemoveAll(outDir)
+	defer os.RemoveAll(outDir)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	tuning.GPU = appConfig.GPU
+	job := &videoJob{
+		paths:        depCheck.Paths,
+		interpolator: depCheck.Engine,
+		format:       frameFormat{Name: "png"},
+		width:        bucket.Width,
+		height:       bucket.Height,
+		tuning:       tuning.resolve(bucket.Width, bucket.Height, depCheck.Engine.autoThreads),
+	}
+
+	start := time.Now()
+	if err := depCheck.Engine.Interpolate(ctx, job, inDir, outDir, frameRate{2, 1}); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	elapsed := time.Since(start)
+
+	count, err := countFiles(outDir)
+	if err != nil || count != frames*2 {
+		result.Error = fmt.Sprintf("输出 %d 帧，预期 %d 帧", count, frames*2)
+		return result
+	}
+	result.FPS = float64(count) / elapsed.Seconds()
+	return result
+}
+
+// estimateInterpolationTime 用基准测试测得的速度估算插帧耗时，画质模式按耗时倍数折算
+// 没有该分辨率档位的测试结果时返回 false
+func (c *Config) estimateInterpolationTime(engine string, width, height int, outputFrames int64, modes effectiveModes) (time.Duration, bool) {
+	profile, ok := c.tuningProfile(engine, width, height)
+	if !ok || profile.FPS <= 0 {
+		return 0, false
+	}
+	seconds := float64(outputFrames) / profile.FPS * modes.Cost()
+	return time.Duration(seconds * float64(time.Second)), true
+}
@@ -33,7 +33,7 @@ case "$OS" in
         echo "🍎 构建 macOS 版本..."
 
         # 构建可执行文件
-        go build -ldflags="-s -w" -o "$BUILD_DIR/$APP_NAME" main.go
+        go build -ldflags="-s -w" -o "$BUILD_DIR/$APP_NAME" .
 
         # 创建 .app 包
         APP_BUNDLE="$BUILD_DIR/$APP_NAME.app"
@@ -90,7 +90,7 @@ EOF
         echo "🐧 构建 Linux 版本..."
 
         # 构建可执行文件
-        go build -ldflags="-s -w" -o "$BUILD_DIR/$APP_NAME" main.go
+        go build -ldflags="-s -w" -o "$BUILD_DIR/$APP_NAME" .
 
         # 创建发布包
         RELEASE_DIR="$BUILD_DIR/$APP_NAME-linux"
@@ -107,7 +107,7 @@ EOF
         echo "🪟 构建 Windows 版本..."
 
         # 构建可执行文件
-        go build -ldflags="-s -w" -o "$BUILD_DIR/$APP_NAME.exe" main.go
+        go build -ldflags="-s -w" -o "$BUILD_DIR/$APP_NAME.exe" .
 
         echo "✅ Windows 版本已创建: $BUILD_DIR/$APP_NAME.exe"
         ;;
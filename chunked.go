@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const minChunkFrames = 16
+
+// videoJob 描述一次处理任务中各阶段共用的参数
+type videoJob struct {
+	paths        *BinaryPaths
+	interpolator Interpolator
+	inputPath    string
+	workDir      string
+	audioPath    string
+	output       *outputTarget
+	ui           jobReporter        // 进度显示
+	scheduler    *resourceScheduler // 为空时不经过调度器，如基准测试
+
+	format frameFormat     // 中间帧格式
+	codec  string          // 最终编码器，由依赖诊断报告选出
+	tuning effectiveTuning // 插帧程序的线程数、分块大小和显卡
+	modes  effectiveModes  // 插帧程序的 UHD 和 TTA 模式
+	vfr    *vfrHandling    // 可变帧率输入的处理方式，固定帧率时为 nil
+
+	width, height         int
+	frames                int64
+	startOffset           float64 // 视频流起始时间相对容器起始时间的偏移（秒）
+	fpsOrigin, fpsTarget  frameRate
+	ratio                 frameRate // AI 插帧输出帧率与原始帧率之比，整数倍或直接到目标帧率的分数
+	needFFMpegInterpolate bool
+}
+
+// stepName 返回插帧步骤在界面上显示的名称，低画质的插帧方式需要明确标出
+func (j *videoJob) stepName() string {
+	if j.interpolator.LowQuality() {
+		return "FFmpeg 插帧（低画质）"
+	}
+	return "AI 插帧"
+}
+
+// runStage 在调度器的资源槽位中运行一个阶段
+func (j *videoJob) runStage(ctx context.Context, kind slotKind, stage func() error) error {
+	if j.scheduler == nil {
+		return stage()
+	}
+	return j.scheduler.run(ctx, kind, stage)
+}
+
+// runFFmpeg 在编码槽位中运行 ffmpeg
+func (j *videoJob) runFFmpeg(ctx context.Context, args []string) error {
+	return j.runStage(ctx, slotEncode, func() error {
+		return runCommand(ctx, j.paths.FFmpeg, args)
+	})
+}
+
+// interpolate 在插帧槽位中运行插帧
+func (j *videoJob) interpolate(ctx context.Context, inDir, outDir string) error {
+	return j.runStage(ctx, slotGPU, func() error {
+		return j.interpolator.Interpolate(ctx, j, inDir, outDir, j.ratio)
+	})
+}
+
+// chunkFramesForLimit 根据临时空间上限计算每段的帧数
+// 每段峰值用量为 N+1 张输入帧加 ratio×(N+1) 张 RIFE 输出帧；
+// 帧数对齐到比值的分母，使每段的时间步与整段一次插帧时一致
+func chunkFramesForLimit(limitBytes int64, width, height int, ratio frameRate, format frameFormat) int {
+	perFrame := (1 + ratio.Float()) * float64(width*height) * format.BytesPerPixel()
+	return ratio.alignDown(max(int(float64(limitBytes)/perFrame)-1, minChunkFrames))
+}
+
+// keptFrames 返回一段 count 张输入帧插帧后需要保留的输出帧数，分段处理和流水线窗口共用：
+// 非最后一段与下一段重叠 1 帧，丢弃重叠帧及其之后的输出，它们由下一段生成
+func keptFrames(ratio frameRate, count int, last bool) int64 {
+	if !last {
+		count--
+	}
+	return ratio.scaleCount(int64(count))
+}
+
+// processChunked 分段处理视频：每段 N 帧（与下一段重叠 1 帧，保证段边界的插值帧不丢失），
+// 拆帧、插帧后立即编码为片段并删除帧文件，最后拼接片段并封装音频
+func processChunked(ctx context.Context, job *videoJob, chunkFrames int) error {
+	inDir := filepath.Join(job.workDir, "in")
+	outDir := filepath.Join(job.workDir, "out")
+	segDir := filepath.Join(job.workDir, "segments")
+	if err := os.MkdirAll(segDir, 0755); err != nil {
+		return fmt.Errorf("创建片段目录失败: %w", err)
+	}
+
+	rifeFrameRate := job.fpsOrigin.Scale(job.ratio)
+	totalChunks := int((job.frames + int64(chunkFrames) - 1) / int64(chunkFrames))
+
+	job.ui.Step(stepExtract, StepRunning, "提取视频帧")
+	job.ui.Step(stepInterp, StepRunning, job.stepName())
+	job.ui.Step(stepMerge, StepRunning, "合并视频")
+
+	var segments []string
+	var overlapHash string // 上一段最后一帧（即本段第一帧）的哈希，用于校验段边界
+	for chunk := 0; ; chunk++ {
+		start := int64(chunk) * int64(chunkFrames)
+		progress := float64(chunk) / float64(max(totalChunks, 1))
+		job.ui.Progress(fmt.Sprintf("分段处理中（第 %d/%d 段）...", chunk+1, max(totalChunks, chunk+1)), 30+progress*60)
+
+		// 清空上一段的帧
+		for _, dir := range []string{inDir, outDir} {
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+
+		// 拆出本段 N+1 帧（最后一帧与下一段第一帧重叠）
+		args := append([]string{"-y"}, job.seekArgs(start)...)
+		args = append(args,
+			"-i", job.inputPath,
+			"-frames:v", fmt.Sprintf("%d", chunkFrames+1),
+		)
+		args = append(args, job.extractArgs()...)
+		if err := job.runFFmpeg(ctx, append(args, job.format.Pattern(inDir))); err != nil {
+			job.ui.Step(stepExtract, StepError, "提取视频帧")
+			return fmt.Errorf("拆帧失败: %w", err)
+		}
+		job.ui.StepProgress(stepExtract, progress)
+
+		extracted, err := countFiles(inDir)
+		if err != nil {
+			return err
+		}
+		if extracted == 0 {
+			break
+		}
+		// 拆出的帧不足 N+1 说明已到达视频末尾
+		last := extracted <= chunkFrames
+
+		// 同一帧解码和写出的结果相同，本段第一帧应与上一段的重叠帧完全一致
+		if overlapHash != "" {
+			if first, err := fileSHA256(job.format.FramePath(inDir, 1)); err == nil && first != overlapHash {
+				job.ui.Log(fmt.Sprintf("⚠️ 第 %d 段的第一帧与上一段的重叠帧不一致，段边界可能有重复或缺失的帧", chunk+1))
+			}
+		}
+		if !last {
+			if overlapHash, err = fileSHA256(job.format.FramePath(inDir, extracted)); err != nil {
+				return err
+			}
+		}
+
+		if err := job.interpolate(ctx, inDir, outDir); err != nil {
+			job.ui.Step(stepInterp, StepError, job.stepName())
+			return fmt.Errorf("%s失败: %w", job.stepName(), err)
+		}
+		job.ui.StepProgress(stepInterp, progress)
+
+		keepFrames := keptFrames(job.ratio, extracted, last)
+
+		segment := filepath.Join(segDir, fmt.Sprintf("%04d.mp4", chunk))
+		if err := job.runFFmpeg(ctx, append([]string{
+			"-y",
+			"-framerate", rifeFrameRate.String(),
+			"-i", job.format.Pattern(outDir),
+			"-frames:v", fmt.Sprintf("%d", keepFrames),
+		}, segmentEncodeArgs(job, segment)...)); err != nil {
+			job.ui.Step(stepMerge, StepError, "合并视频")
+			return fmt.Errorf("编码片段失败: %w", err)
+		}
+		segments = append(segments, segment)
+		job.ui.StepProgress(stepMerge, progress)
+
+		if last {
+			break
+		}
+	}
+
+	os.RemoveAll(inDir)
+	os.RemoveAll(outDir)
+	job.ui.StepProgress(stepExtract, 1.0)
+	job.ui.Step(stepExtract, StepCompleted, "提取视频帧")
+	job.ui.StepProgress(stepInterp, 1.0)
+	job.ui.Step(stepInterp, StepCompleted, job.stepName())
+
+	if len(segments) == 0 {
+		job.ui.Step(stepMerge, StepError, "合并视频")
+		return fmt.Errorf("未能从视频中提取到任何帧")
+	}
+
+	// 拼接片段并封装音频
+	job.ui.Progress("正在封装最终视频...", 90)
+	listPath := filepath.Join(job.workDir, "segments.txt")
+	if err := writeConcatList(listPath, segments); err != nil {
+		return err
+	}
+
+	args := []string{
+		"-y",
+		"-f", "concat", "-safe", "0", "-i", listPath,
+		"-i", job.audioPath,
+	}
+	if job.needFFMpegInterpolate {
+		// 片段是 RIFE 帧率的中间视频，在拼接后统一补充到目标帧率
+		args = append(args,
+			"-filter:v", fmt.Sprintf("minterpolate=fps=%s:mi_mode=mci:mc_mode=aobmc:me_mode=bidir_ref:vsbmc=1", job.fpsTarget),
+			"-c:v", job.codec,
+			"-b:v", "15M",
+			"-pix_fmt", "yuv420p",
+		)
+	} else {
+		args = append(args, "-c:v", "copy")
+	}
+	args = append(args, "-c:a", "copy", "-shortest", job.output.TempPath)
+
+	if err := job.runFFmpeg(ctx, args); err != nil {
+		job.ui.Step(stepMerge, StepError, "合并视频")
+		return fmt.Errorf("封装视频失败: %w", err)
+	}
+	if err := job.output.Commit(); err != nil {
+		job.ui.Step(stepMerge, StepError, "合并视频")
+		return err
+	}
+	job.ui.StepProgress(stepMerge, 1.0)
+	job.ui.Step(stepMerge, StepCompleted, "合并视频")
+
+	return nil
+}
+
+// seekArgs 返回从第 frame 帧（从 0 开始）拆帧的输入定位参数
+// -ss 放在 -i 之前时 ffmpeg 先跳到之前的关键帧再解码，并丢弃时间戳早于定位点的帧；
+// 定位点相对容器的起始时间，因此要加上视频流的起始偏移，并提前半帧，使浮点误差不会让边界帧重复或丢失
+func (j *videoJob) seekArgs(frame int64) []string {
+	if frame <= 0 {
+		return nil
+	}
+	seek := j.startOffset + (float64(frame)-0.5)/j.fpsOrigin.Float()
+	return []string{"-ss", fmt.Sprintf("%.6f", max(seek, 0))}
+}
+
+func fileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// segmentEncodeArgs 返回片段的编码参数
+// 需要 minterpolate 时片段只是中间结果，使用快速的高质量编码；否则直接使用最终编码参数以便无损拼接
+func segmentEncodeArgs(job *videoJob, segment string) []string {
+	if job.needFFMpegInterpolate {
+		return []string{
+			"-c:v", "libx264",
+			"-preset", "ultrafast",
+			"-crf", "18",
+			"-pix_fmt", "yuv420p",
+			segment,
+		}
+	}
+	return []string{
+		"-c:v", job.codec,
+		"-b:v", "15M",
+		"-pix_fmt", "yuv420p",
+		segment,
+	}
+}
+
+// writeConcatList 写入 ffmpeg concat demuxer 使用的文件列表
+func writeConcatList(listPath string, files []string) error {
+	var b strings.Builder
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	}
+	if err := os.WriteFile(listPath, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("写入片段列表失败: %w", err)
+	}
+	return nil
+}
+
+func countFiles(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			count++
+		}
+	}
+	return count, nil
+}
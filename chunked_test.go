@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+// 按分段和流水线窗口的方式切分：每段 unit 帧加上与下一段重叠的 1 帧，
+// 各段保留的输出帧数之和必须等于整段一次插帧的帧数，且非最后一段的输出帧数恰好为整数
+func TestKeptFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		ratio  frameRate
+		frames int
+	}{
+		{"2 倍", frameRate{2, 1}, 1000},
+		{"24 到 60 帧", frameRate{5, 2}, 1001},
+		{"23.976 到 60 帧", frameRate{1001, 400}, 5000},
+		{"29.97 到 60 帧", frameRate{1001, 500}, 4321},
+		{"59.94 到 144 帧", frameRate{3003, 1250}, 12345},
+		{"不满一段", frameRate{5, 2}, 7},
+	}
+	for _, tt := range tests {
+		for name, unit := range map[string]int{
+			"流水线窗口": streamWindowSize(tt.ratio),
+			"分段":    tt.ratio.alignDown(minChunkFrames),
+		} {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				var total int64
+				for start := 1; ; start += unit {
+					end := min(start+unit, tt.frames)
+					last := end == tt.frames
+					kept := keptFrames(tt.ratio, end-start+1, last)
+					if !last && kept*tt.ratio.Den != int64(unit)*tt.ratio.Num {
+						t.Fatalf("第 %d 帧开始的一段保留 %d 帧，不是 %d 帧的 %v 倍", start, kept, unit, tt.ratio)
+					}
+					total += kept
+					if last {
+						break
+					}
+				}
+				if want := tt.ratio.scaleCount(int64(tt.frames)); total != want {
+					t.Errorf("共保留 %d 帧，want %d", total, want)
+				}
+			})
+		}
+	}
+}
+
+func TestStreamWindowSize(t *testing.T) {
+	tests := []struct {
+		ratio frameRate
+		want  int
+	}{
+		{frameRate{2, 1}, 100},
+		{frameRate{5, 2}, 100},
+		{frameRate{12, 5}, 100},
+		{frameRate{1001, 400}, 1600},
+		{frameRate{1001, 500}, 2000},
+		{frameRate{3003, 1250}, 5000},
+	}
+	for _, tt := range tests {
+		if got := streamWindowSize(tt.ratio); got != tt.want {
+			t.Errorf("streamWindowSize(%v) = %d, want %d", tt.ratio, got, tt.want)
+		}
+	}
+}
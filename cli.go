@@ -0,0 +1,561 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// cliCommands 是 runCLI 支持的子命令
+var cliCommands = []string{"cleanup", "deps", "doctor", "models", "tuning", "benchmark", "process", "probe", "help", "-h", "-help", "--help"}
+
+// isCLIInvocation 判断是否以命令行子命令启动
+// 只有第一个参数是已知命令时才进入命令行模式：用本程序打开视频、把文件拖到程序上时参数是文件路径，
+// macOS 旧版本还会给 .app 传入 -psn_xxx 参数，这些情况都应启动图形界面
+func isCLIInvocation(args []string) bool {
+	return len(args) > 0 && slices.Contains(cliCommands, args[0])
+}
+
+// runCLI 执行命令行子命令，返回进程退出码
+func runCLI(args []string) int {
+	switch args[0] {
+	case "cleanup":
+		return cmdCleanup(args[1:])
+	case "deps":
+		return cmdDeps(args[1:])
+	case "doctor":
+		return cmdDoctor(args[1:])
+	case "models":
+		return cmdModels(args[1:])
+	case "tuning":
+		return cmdTuning(args[1:])
+	case "benchmark":
+		return cmdBenchmark(args[1:])
+	case "process":
+		return cmdProcess(args[1:])
+	case "probe":
+		return cmdProbe(args[1:])
+	case "help", "-h", "-help", "--help":
+		printUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", args[0])
+		printUsage()
+		return 2
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `用法: fps2x [命令] [参数]
+
+不带命令时启动图形界面。
+
+命令:
+  cleanup    列出并删除崩溃或中断遗留的 work_* 工作目录
+  deps       显示 ffmpeg、ffprobe、RIFE 和模型的路径及来源
+  doctor     运行各依赖，检查版本、编码器、滤镜和模型文件
+  models     列出当前插帧引擎的可用模型及其能力
+             models import <文件或文件夹> 从 .zip、.tar.gz 或文件夹导入模型
+             models remove <名称>         删除导入的模型
+  tuning     查看或修改插帧程序的线程数、分块大小、显卡、UHD 和 TTA 画质模式以及后台优先级
+  benchmark  测试各分辨率下不同线程数和分块大小的速度，保存最快的组合
+  probe      显示视频的流、编码、时长、帧率、旋转和色彩信息
+  process    批量处理视频，按插帧槽位、编码槽位和磁盘预算让多个任务同时进行
+  help       显示此帮助`)
+}
+
+func cmdCleanup(args []string) int {
+	fs := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	workDir := fs.String("work-dir", appConfig.WorkDir, "工作目录位置（默认使用设置中的值）")
+	dryRun := fs.Bool("dry-run", false, "只列出遗留目录，不删除")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	appConfig.WorkDir = *workDir
+
+	orphans, err := findOrphanWorkDirs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "扫描工作目录失败: %v\n", err)
+		return 1
+	}
+	if len(orphans) == 0 {
+		fmt.Println("没有遗留的工作目录")
+		return 0
+	}
+
+	var total int64
+	for _, dir := range orphans {
+		fmt.Printf("%10s  %s\n", formatBytes(dir.Size), dir.Path)
+		total += dir.Size
+	}
+	fmt.Printf("共 %d 个目录，%s\n", len(orphans), formatBytes(total))
+
+	if *dryRun {
+		return 0
+	}
+
+	freed, err := removeWorkDirs(orphans)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("已删除，释放 %s\n", formatBytes(freed))
+	return 0
+}
+
+// addDependencyFlags 注册覆盖依赖路径的参数，优先级高于环境变量和 binaries 目录
+func addDependencyFlags(fs *flag.FlagSet) {
+	fs.StringVar(&appConfig.FFmpegPath, "ffmpeg", appConfig.FFmpegPath, "ffmpeg 路径（默认依次查找 FPS2X_FFMPEG、binaries 目录、PATH）")
+	fs.StringVar(&appConfig.FFprobePath, "ffprobe", appConfig.FFprobePath, "ffprobe 路径（默认依次查找 FPS2X_FFPROBE、binaries 目录、PATH）")
+	fs.StringVar(&appConfig.RIFEPath, "rife", appConfig.RIFEPath, "rife-ncnn-vulkan 路径（默认依次查找 FPS2X_RIFE、binaries 目录、PATH）")
+	fs.StringVar(&appConfig.Engine, "engine", appConfig.Engine, "插帧引擎："+strings.Join(interpolatorNames(), "、")+"（RIFE 以外的程序路径通过 FPS2X_IFRNET 等环境变量指定；minterpolate 和 framerate 为 ffmpeg 滤镜，画质较低）")
+	fs.StringVar(&appConfig.ModelDir, "model-dir", appConfig.ModelDir, "模型目录（默认依次查找 FPS2X_MODEL_DIR、binaries 目录、用户模型目录、插帧程序所在目录）")
+	fs.StringVar(&appConfig.Model, "model", appConfig.Model, "模型名称，如 rife-v4.6（-model-dir 指定时以其为准）")
+}
+
+// addTuningFlags 添加插帧程序高级参数，写入 appConfig
+func addTuningFlags(fs *flag.FlagSet) {
+	fs.StringVar(&appConfig.Threads, "threads", appConfig.Threads, "插帧线程数 load:proc:save，如 4:8:4；auto 表示按分辨率自动计算")
+	fs.IntVar(&appConfig.TileSize, "tile", appConfig.TileSize, "插帧分块大小（32 的倍数），0 表示自动；显存不足时调小")
+	fs.StringVar(&appConfig.GPU, "gpu", appConfig.GPU, "插帧使用的显卡编号，多块显卡用逗号分隔（如 0,1，处理线程按显卡拆分），-1 表示使用 CPU；auto 表示自动")
+	fs.StringVar(&appConfig.UHD, "uhd", appConfig.UHD, "UHD 模式：auto（4K 视频自动开启）、on、off")
+	fs.BoolVar(&appConfig.SpatialTTA, "tta-spatial", appConfig.SpatialTTA, fmt.Sprintf("启用空间 TTA，画质更好，插帧耗时约为 %.0f 倍", spatialTTACost))
+	fs.BoolVar(&appConfig.TemporalTTA, "tta-temporal", appConfig.TemporalTTA, fmt.Sprintf("启用时间 TTA，插帧耗时约为 %.0f 倍", temporalTTACost))
+}
+
+// checkTileFlag 拒绝为不支持 -t 的插帧程序（RIFE、IFRNet 和 ffmpeg 滤镜）指定分块大小
+func checkTileFlag(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "tile" && appConfig.TileSize != 0 && !engineHasTileSize(appConfig.interpolatorName()) {
+			err = fmt.Errorf("%s 不支持分块大小（-t），请去掉 -tile 参数", appConfig.interpolatorName())
+		}
+	})
+	return err
+}
+
+// addPriorityFlags 添加后台优先级和 ffmpeg 线程上限参数，写入 appConfig
+func addPriorityFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&appConfig.BackgroundPriority, "background", appConfig.BackgroundPriority, "后台优先级：降低 ffmpeg 和插帧程序的 CPU 和 I/O 优先级")
+	fs.IntVar(&appConfig.FFmpegThreads, "ffmpeg-threads", appConfig.FFmpegThreads, "ffmpeg 编码和滤镜线程数上限，0 表示不限制")
+}
+
+// addSchedulerFlags 添加同时处理多个任务时的资源限制参数，写入 appConfig
+func addSchedulerFlags(fs *flag.FlagSet) {
+	fs.IntVar(&appConfig.GPUSlots, "gpu-slots", appConfig.GPUSlots, fmt.Sprintf("同时运行的插帧阶段数，0 表示默认值 %d", defaultGPUSlots))
+	fs.IntVar(&appConfig.EncodeSlots, "encode-slots", appConfig.EncodeSlots, fmt.Sprintf("同时运行的拆帧、编码阶段数，0 表示默认值 %d", defaultEncodeSlots))
+	fs.Float64Var(&appConfig.DiskBudgetGB, "disk-budget", appConfig.DiskBudgetGB, "所有任务临时文件的总预算（GB），0 表示不限制")
+}
+
+func cmdDeps(args []string) int {
+	fs := flag.NewFlagSet("deps", flag.ContinueOnError)
+	addDependencyFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	depCheck, err := checkDependencies()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "依赖检查失败: %v\n", err)
+		return 1
+	}
+
+	printDependencyPaths(depCheck)
+	if !depCheck.Ready {
+		fmt.Fprintf(os.Stderr, "依赖错误: %s\n", depCheck.Error)
+		return 1
+	}
+	if depCheck.Fallback != "" {
+		fmt.Fprintf(os.Stderr, "%s\n将回退到 ffmpeg 滤镜插帧，画质较低\n", depCheck.Fallback)
+	}
+	return 0
+}
+
+func printDependencyPaths(depCheck *DependencyCheck) {
+	paths := depCheck.Paths
+	rows := []struct {
+		name   string
+		path   string
+		source DependencySource
+	}{
+		{"ffmpeg", paths.FFmpeg, depCheck.Sources.FFmpeg},
+		{"ffprobe", paths.FFprobe, depCheck.Sources.FFprobe},
+		{depCheck.Engine.Name, paths.Interpolator, depCheck.Sources.Interpolator},
+		{"model", paths.Model, depCheck.Sources.Model},
+	}
+	for _, row := range rows {
+		path := row.path
+		if path == "" {
+			path = "-"
+		}
+		fmt.Printf("%-8s %s（%s）\n", row.name, path, row.source)
+	}
+}
+
+func cmdDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	addDependencyFlags(fs)
+	jsonOutput := fs.Bool("json", false, "以 JSON 格式输出报告")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	depCheck, err := checkDependencies()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "依赖检查失败: %v\n", err)
+		return 1
+	}
+	report := runDoctor(context.Background(), depCheck)
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(report)
+	}
+
+	if len(report.Problems()) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func cmdProbe(args []string) int {
+	fs := flag.NewFlagSet("probe", flag.ContinueOnError)
+	addDependencyFlags(fs)
+	jsonOutput := fs.Bool("json", false, "以 JSON 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "用法: fps2x probe [参数] <视频文件>")
+		return 2
+	}
+
+	depCheck, err := checkDependencies()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "依赖检查失败: %v\n", err)
+		return 1
+	}
+	if depCheck.Paths.FFprobe == "" {
+		fmt.Fprintf(os.Stderr, "依赖错误: %s\n", depCheck.Error)
+		return 1
+	}
+	info, err := probeMedia(context.Background(), depCheck.Paths.FFprobe, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取视频信息失败: %v\n", err)
+		return 1
+	}
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(info)
+		video, _ := info.Video()
+		if analysis, err := detectVFR(context.Background(), depCheck.Paths.FFprobe, fs.Arg(0), video); err != nil {
+			fmt.Printf("可变帧率: 无法检测（%v）\n", err)
+		} else if analysis.Variable {
+			fmt.Printf("可变帧率: 是，%s；名义帧率 %s\n", analysis.Reason, analysis.Nominal.Label())
+		} else {
+			fmt.Println("可变帧率: 否")
+		}
+	}
+	return 0
+}
+
+func cmdModels(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "import":
+			return cmdModelsImport(args[1:])
+		case "remove":
+			return cmdModelsRemove(args[1:])
+		}
+	}
+
+	fs := flag.NewFlagSet("models", flag.ContinueOnError)
+	addDependencyFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	depCheck, err := checkDependencies()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "依赖检查失败: %v\n", err)
+		return 1
+	}
+
+	models := discoverModels(depCheck.Engine, depCheck.Paths.Interpolator, &bundledDir{})
+	if len(models) == 0 {
+		fmt.Printf("没有找到 %s 的模型目录（%s*）\n", depCheck.Engine.Label, depCheck.Engine.ModelPrefix)
+		return 1
+	}
+
+	current := appConfig.modelName()
+	for _, model := range models {
+		mark := " "
+		if model.Name == current {
+			mark = "*"
+		}
+		status := "2的幂倍插帧"
+		if model.ArbitraryTimestep {
+			status = "任意时间步"
+		}
+		if !model.Valid() {
+			status = "无效: " + model.Error
+		}
+		fmt.Printf("%s %-16s %-10s %10s  %s\n  %s\n", mark, model.Name, status, formatBytes(model.Size), model.Source, model.Path)
+	}
+	return 0
+}
+
+func cmdModelsImport(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "用法: fps2x models import <.zip|.tar.gz|文件夹>")
+		return 2
+	}
+
+	models, err := importModel(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导入失败: %v\n", err)
+		return 1
+	}
+	for _, model := range models {
+		fmt.Printf("已导入 %s（%s）: %s\n", model.Name, formatBytes(model.Size), model.Path)
+	}
+	return 0
+}
+
+func cmdModelsRemove(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "用法: fps2x models remove <名称>")
+		return 2
+	}
+
+	if err := removeModel(args[0]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("已删除 %s\n", args[0])
+	return 0
+}
+
+func cmdTuning(args []string) int {
+	fs := flag.NewFlagSet("tuning", flag.ContinueOnError)
+	addTuningFlags(fs)
+	addPriorityFlags(fs)
+	resolution := fs.String("resolution", "1920x1080", "按该分辨率显示实际使用的参数")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var width, height int
+	if _, err := fmt.Sscanf(*resolution, "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+		fmt.Fprintf(os.Stderr, "无效的分辨率: %s\n", *resolution)
+		return 2
+	}
+	if appConfig.FFmpegThreads < 0 {
+		fmt.Fprintln(os.Stderr, "ffmpeg 线程上限不能为负数")
+		return 2
+	}
+	for _, validate := range []func() error{appConfig.EngineTuning.validate, appConfig.QualityModes.validate} {
+		if err := validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if err := checkTileFlag(fs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// 指定了参数时保存到设置，界面和之后的任务都会使用
+	changed := false
+	fs.Visit(func(f *flag.Flag) {
+		changed = changed || f.Name != "resolution"
+	})
+	if changed {
+		if isAutoValue(appConfig.Threads) {
+			appConfig.Threads = ""
+		}
+		if isAutoValue(appConfig.GPU) {
+			appConfig.GPU = ""
+		}
+		if isAutoValue(appConfig.UHD) {
+			appConfig.UHD = ""
+		}
+		if err := saveConfig(appConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "保存设置失败: %v\n", err)
+			return 1
+		}
+		fmt.Println("已保存")
+	}
+	fmt.Printf("%s: %s\n", *resolution, appConfig.jobTuning(appConfig.interpolatorName(), width, height))
+	modes := appConfig.QualityModes.resolve(appConfig.engine(), width, height)
+	fmt.Printf("画质模式: %s（插帧耗时约为默认的 %.0f 倍）\n", modes, modes.Cost())
+	fmt.Printf("后台优先级: %v  ffmpeg 线程上限: %d\n", appConfig.BackgroundPriority, appConfig.FFmpegThreads)
+	return 0
+}
+
+func cmdBenchmark(args []string) int {
+	fs := flag.NewFlagSet("benchmark", flag.ContinueOnError)
+	addDependencyFlags(fs)
+	clip := fs.String("clip", "", "用于测试的视频片段（默认使用 ffmpeg 生成的测试画面）")
+	frames := fs.Int("frames", defaultBenchmarkFrames, "每个分辨率使用的输入帧数")
+	buckets := fs.String("buckets", strings.Join(resolutionBucketNames(), ","), "要测试的分辨率档位，逗号分隔")
+	tiles := fs.String("tiles", "0", "要测试的分块大小，逗号分隔，0 表示自动")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	opts := benchmarkOptions{Clip: *clip, Frames: *frames}
+	for _, name := range strings.Split(*buckets, ",") {
+		bucket, ok := findResolutionBucket(strings.TrimSpace(name))
+		if !ok {
+			fmt.Fprintf(os.Stderr, "未知的分辨率档位: %s（可选 %s）\n", name, strings.Join(resolutionBucketNames(), "、"))
+			return 2
+		}
+		opts.Buckets = append(opts.Buckets, bucket)
+	}
+	for _, text := range strings.Split(*tiles, ",") {
+		tile, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil || (EngineTuning{TileSize: tile}).validate() != nil {
+			fmt.Fprintf(os.Stderr, "无效的分块大小: %s\n", text)
+			return 2
+		}
+		if tile != 0 && !engineHasTileSize(appConfig.interpolatorName()) {
+			fmt.Fprintf(os.Stderr, "%s 不支持分块大小（-t），-tiles 只能为 0\n", appConfig.interpolatorName())
+			return 2
+		}
+		opts.Tiles = append(opts.Tiles, tile)
+	}
+
+	depCheck, err := checkDependencies()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "依赖检查失败: %v\n", err)
+		return 1
+	}
+	if !depCheck.Ready {
+		fmt.Fprintf(os.Stderr, "依赖错误: %s\n", depCheck.Error)
+		return 1
+	}
+
+	profiles, _, err := runBenchmark(context.Background(), depCheck, opts, func(done, total int, result benchmarkResult) {
+		fmt.Printf("[%d/%d] %s\n", done, total, result)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "基准测试失败: %v\n", err)
+		return 1
+	}
+	if len(profiles) == 0 {
+		fmt.Fprintln(os.Stderr, "所有组合都失败，没有保存结果")
+		return 1
+	}
+
+	// 重新读取设置再保存，避免把本次的依赖路径参数写入设置
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println("\n最佳组合:")
+	for _, profile := range profiles {
+		cfg.saveTuningProfile(profile)
+		fmt.Printf("  %s\n", profile)
+	}
+	if err := saveConfig(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "保存设置失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func cmdProcess(args []string) int {
+	fs := flag.NewFlagSet("process", flag.ContinueOnError)
+	addDependencyFlags(fs)
+	addTuningFlags(fs)
+	addPriorityFlags(fs)
+	addSchedulerFlags(fs)
+	fs.StringVar(&appConfig.VFRMode, "vfr", appConfig.VFRMode, "可变帧率视频的处理方式：cfr（统一为固定帧率）或 timestamps（保留原始时间戳）")
+	fs.StringVar(&appConfig.VFRRate, "vfr-rate", appConfig.VFRRate, "统一为固定帧率时使用的帧率，如 30 或 30000/1001；auto 表示按平均帧率自动选择")
+	mode := fs.String("mode", "2x", "输出模式："+strings.Join(outputModeNames(), "、")+"（2x、3x、4x、8x 为帧率的整数倍，其余为固定帧率；29.97 等 NTSC 帧率的视频选 59.94fps 可直接翻倍）")
+	jobs := fs.Int("jobs", 0, "同时进行的任务数，0 表示插帧槽位与编码槽位之和")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	files := fs.Args()
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "用法: fps2x process [参数] <视频文件>...")
+		return 2
+	}
+	if _, ok := findOutputMode(*mode); !ok {
+		fmt.Fprintf(os.Stderr, "未知的输出模式: %s（可选 %s）\n", *mode, strings.Join(outputModeNames(), "、"))
+		return 2
+	}
+	for _, validate := range []func() error{appConfig.SchedulerSettings.validate, appConfig.VFRSettings.validate, func() error { return checkTileFlag(fs) }} {
+		if err := validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	workers := *jobs
+	if workers <= 0 {
+		scheduler := currentScheduler()
+		workers = cap(scheduler.gpu) + cap(scheduler.encode)
+	}
+	workers = min(workers, len(files))
+	fmt.Printf("%s  同时处理 %d 个任务\n", appConfig.SchedulerSettings, workers)
+
+	// 任务按顺序领取，各阶段由调度器按槽位交错运行
+	var mu sync.Mutex
+	reporters := make([]*consoleReporter, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				processVideo(files[i], *mode, reporters[i])
+			}
+		}()
+	}
+	for i, file := range files {
+		reporters[i] = &consoleReporter{name: filepath.Base(file), out: os.Stdout, mu: &mu}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	failed := 0
+	fmt.Println("\n结果:")
+	for i, r := range reporters {
+		if r.failed || r.output == "" {
+			failed++
+			fmt.Printf("  ❌ %s\n", files[i])
+		} else {
+			fmt.Printf("  ✅ %s -> %s\n", files[i], r.output)
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config 是 GUI 和命令行共用的用户设置，保存在用户配置目录下
+type Config struct {
+	WorkDir string `json:"work_dir,omitempty"` // 帧缓存目录，留空则使用输出目录
+
+	// 显式指定的依赖路径，优先于环境变量、binaries 目录和系统 PATH
+	FFmpegPath  string `json:"ffmpeg_path,omitempty"`
+	FFprobePath string `json:"ffprobe_path,omitempty"`
+	RIFEPath    string `json:"rife_path,omitempty"`
+	ModelDir    string `json:"model_dir,omitempty"`
+
+	// RIFE 以外的插帧程序路径，按引擎名称索引
+	EnginePaths map[string]string `json:"engine_paths,omitempty"`
+
+	// 插帧引擎（rife/ifrnet/cain/dain）或 ffmpeg 滤镜（minterpolate/framerate），留空则使用 RIFE
+	Engine string `json:"engine,omitempty"`
+
+	// 使用的模型名称（模型目录名），留空或不属于当前引擎时使用引擎的默认模型；ModelDir 指定时以其为准
+	Model string `json:"model,omitempty"`
+
+	// 临时空间上限（GB），预计用量超过该值时分段处理，0 表示不限制
+	ScratchLimitGB float64 `json:"scratch_limit_gb,omitempty"`
+
+	// 流水线模式：拆帧、插帧和编码同时进行
+	Pipeline bool `json:"pipeline,omitempty"`
+
+	// 按关键帧切段，用多个 ffmpeg 进程并行拆帧和编码（仅 libx264）
+	ParallelSegments bool `json:"parallel_segments,omitempty"`
+
+	// 后台优先级：降低子进程的 CPU 和 I/O 优先级，处理时不影响其他程序
+	BackgroundPriority bool `json:"background_priority,omitempty"`
+
+	// ffmpeg 编码和滤镜线程数上限，0 表示不限制
+	FFmpegThreads int `json:"ffmpeg_threads,omitempty"`
+
+	// 可变帧率视频的处理方式
+	VFRSettings
+
+	// 同时处理多个任务时的插帧槽位、编码槽位和磁盘预算
+	SchedulerSettings
+
+	// 插帧程序的线程数、分块大小和显卡，留空表示自动
+	EngineTuning
+
+	// 插帧程序的 UHD 和 TTA 画质模式
+	QualityModes
+
+	// 基准测试得出的各分辨率最佳参数，手动指定的参数优先
+	TuningProfiles []TuningProfile `json:"tuning_profiles,omitempty"`
+
+	// 已提示过运行基准测试，不再在启动时询问
+	BenchmarkPrompted bool `json:"benchmark_prompted,omitempty"`
+
+	// 中间帧格式（jpg/png/webp，留空为 jpg）及有损格式的质量（1-100）
+	FrameFormat  string `json:"frame_format,omitempty"`
+	FrameQuality int    `json:"frame_quality,omitempty"`
+
+	// 用户保存的预设，内置预设不写入配置
+	Presets []Preset `json:"presets,omitempty"`
+}
+
+func (c *Config) engine() *interpolationEngine {
+	if engine, ok := findEngine(c.Engine); ok {
+		return engine
+	}
+	engine, _ := findEngine(defaultEngineName)
+	return engine
+}
+
+// ffmpegBackend 返回设置中选择的 ffmpeg 滤镜插帧方式
+func (c *Config) ffmpegBackend() (*ffmpegInterpolator, bool) {
+	return findFFmpegInterpolator(c.Engine)
+}
+
+// interpolatorName 返回设置中选择的插帧方式名称，供界面显示
+func (c *Config) interpolatorName() string {
+	if backend, ok := c.ffmpegBackend(); ok {
+		return backend.name
+	}
+	return c.engine().Name
+}
+
+// enginePath 返回设置中指定的插帧程序路径，RIFE 沿用原有的 rife_path
+func (c *Config) enginePath(engine *interpolationEngine) string {
+	if engine.Name == defaultEngineName {
+		return c.RIFEPath
+	}
+	return c.EnginePaths[engine.Name]
+}
+
+func (c *Config) modelName() string {
+	engine := c.engine()
+	if c.Model == "" || !engine.ownsModel(c.Model) {
+		return engine.DefaultModel
+	}
+	return c.Model
+}
+
+func (c *Config) frameFormat() frameFormat {
+	return newFrameFormat(c.FrameFormat, c.FrameQuality)
+}
+
+func (c *Config) scratchLimitBytes() int64 {
+	return int64(c.ScratchLimitGB * 1024 * 1024 * 1024)
+}
+
+var appConfig = &Config{}
+
+func getConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "fps2x", "config.json"), nil
+}
+
+// loadConfig 读取配置文件，文件不存在时返回默认配置
+func loadConfig() (*Config, error) {
+	cfg := &Config{}
+
+	configPath, err := getConfigPath()
+	if err != nil {
+		return cfg, err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("读取配置失败: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return &Config{}, fmt.Errorf("解析配置失败: %w", err)
+	}
+	return cfg, nil
+}
+
+func saveConfig(cfg *Config) error {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+	return nil
+}
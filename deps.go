@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// DependencySource 表示依赖文件的来源
+type DependencySource string
+
+const (
+	SourceNone       DependencySource = ""
+	SourceConfig     DependencySource = "config"     // 设置或命令行参数
+	SourceEnv        DependencySource = "env"        // 环境变量
+	SourceBundled    DependencySource = "bundled"    // 可执行文件旁的 binaries 目录
+	SourceEmbedded   DependencySource = "embedded"   // 内嵌文件解压到的缓存目录
+	SourcePATH       DependencySource = "PATH"       // 系统 PATH
+	SourceNearEngine DependencySource = "engine-dir" // 插帧程序所在目录（仅模型）
+	SourceUser       DependencySource = "user"       // 用户模型目录中导入的模型（仅模型）
+)
+
+func (s DependencySource) String() string {
+	switch s {
+	case SourceConfig:
+		return "设置/参数"
+	case SourceEnv:
+		return "环境变量"
+	case SourceBundled:
+		return "binaries 目录"
+	case SourceEmbedded:
+		return "内嵌文件"
+	case SourcePATH:
+		return "系统 PATH"
+	case SourceNearEngine:
+		return "插帧程序所在目录"
+	case SourceUser:
+		return "用户导入"
+	default:
+		return "未找到"
+	}
+}
+
+// BinarySources 记录 BinaryPaths 中每一项的来源
+type BinarySources struct {
+	FFmpeg       DependencySource
+	FFprobe      DependencySource
+	Interpolator DependencySource
+	Model        DependencySource
+}
+
+// dependencySpec 描述一个依赖的查找方式
+type dependencySpec struct {
+	label      string // 错误信息中显示的名称
+	configPath string // 设置或命令行参数中指定的路径
+	envVar     string
+	fileName   string // binaries 目录中的文件名，同时用于在 PATH 中查找
+	isDir      bool
+}
+
+// bundledDir 延迟解析 binaries 目录：只有前面的来源都未命中时才会解压内嵌文件
+type bundledDir struct {
+	resolved bool
+	path     string
+	source   DependencySource
+	err      error
+}
+
+func (b *bundledDir) get() (string, DependencySource, error) {
+	if !b.resolved {
+		b.path, b.source, b.err = resolveBinariesPath()
+		b.resolved = true
+	}
+	return b.path, b.source, b.err
+}
+
+// resolveDependency 按以下顺序查找依赖：设置/参数、环境变量、binaries 目录（或内嵌文件）、系统 PATH
+// 显式指定的路径不存在时直接报错，不再回退到其他来源
+func resolveDependency(spec dependencySpec, bundled *bundledDir) (string, DependencySource, error) {
+	explicit := []struct {
+		path   string
+		source DependencySource
+		name   string
+	}{
+		{spec.configPath, SourceConfig, "设置中"},
+		{os.Getenv(spec.envVar), SourceEnv, "环境变量 " + spec.envVar},
+	}
+	for _, e := range explicit {
+		if e.path == "" {
+			continue
+		}
+		if _, err := os.Stat(e.path); err != nil {
+			return "", SourceNone, fmt.Errorf("%s指定的 %s 不存在: %s", e.name, spec.label, e.path)
+		}
+		return e.path, e.source, nil
+	}
+
+	name := spec.fileName
+	if runtime.GOOS == "windows" && !spec.isDir {
+		name += ".exe"
+	}
+
+	if dir, source, err := bundled.get(); err == nil {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, source, nil
+		}
+	}
+
+	if !spec.isDir {
+		if path, err := exec.LookPath(spec.fileName); err == nil {
+			return path, SourcePATH, nil
+		}
+	}
+
+	return "", SourceNone, fmt.Errorf("%s 未找到", spec.label)
+}
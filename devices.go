@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ncnn 初始化 Vulkan 时为每个设备打印一行，如 "[0 NVIDIA GeForce RTX 3080]  queueC=2[8]  queueG=0[16]"
+var gpuDevicePattern = regexp.MustCompile(`(?m)^\[(\d+) (.+?)\]\s+queueC=`)
+
+// GPUDevice 是插帧程序能够使用的 Vulkan 设备
+type GPUDevice struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// parseGPUSetting 解析显卡设置：空值或 auto 表示自动，-1 表示 CPU，其余为逗号分隔的设备编号
+func parseGPUSetting(value string) ([]int, error) {
+	if isAutoValue(value) {
+		return nil, nil
+	}
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < -1 {
+			return nil, fmt.Errorf("显卡编号必须是 -1（CPU）或非负整数，多块显卡用逗号分隔: %s", value)
+		}
+		ids = append(ids, id)
+	}
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	if len(ids) > 1 && (len(slices.Compact(sorted)) != len(ids) || slices.Contains(ids, -1)) {
+		return nil, fmt.Errorf("显卡编号不能重复，-1（CPU）不能与显卡同时使用: %s", value)
+	}
+	return ids, nil
+}
+
+// 设备列表在运行期间不会变化，按插帧程序和模型缓存：每个任务开始前都会诊断依赖，不必每次都启动插帧程序
+var gpuDeviceCache = struct {
+	sync.Mutex
+	results map[[2]string]gpuDeviceResult
+}{results: map[[2]string]gpuDeviceResult{}}
+
+type gpuDeviceResult struct {
+	devices []GPUDevice
+	err     error
+}
+
+// cachedGPUDevices 返回缓存的设备列表，首次调用时运行 listGPUDevices
+// 同时开始的多个任务只启动一次插帧程序；因 ctx 取消而失败时不缓存
+func cachedGPUDevices(ctx context.Context, binary, model string) ([]GPUDevice, error) {
+	gpuDeviceCache.Lock()
+	defer gpuDeviceCache.Unlock()
+	key := [2]string{binary, model}
+	if result, ok := gpuDeviceCache.results[key]; ok {
+		return result.devices, result.err
+	}
+	devices, err := listGPUDevices(ctx, binary, model)
+	if ctx.Err() == nil {
+		gpuDeviceCache.results[key] = gpuDeviceResult{devices, err}
+	}
+	return devices, err
+}
+
+// listGPUDevices 运行插帧程序处理一个空目录，从 ncnn 初始化时的输出中解析设备列表
+// -h 在初始化 Vulkan 之前就退出，无法列出设备
+func listGPUDevices(ctx context.Context, binary, model string) ([]GPUDevice, error) {
+	dir, err := os.MkdirTemp("", "fps2x-gpu-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	inDir, outDir := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+	for _, sub := range []string{inDir, outDir} {
+		if err := os.Mkdir(sub, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorCommandTimeout)
+	defer cancel()
+
+	args := []string{"-i", inDir, "-o", outDir}
+	if model != "" {
+		args = append(args, "-m", model)
+	}
+	cmd := exec.CommandContext(ctx, binary, args...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// 没有输入帧时程序可能以非零状态退出，只要打印了设备列表即可
+	runErr := cmd.Run()
+
+	var devices []GPUDevice
+	for _, match := range gpuDevicePattern.FindAllStringSubmatch(output.String(), -1) {
+		id, _ := strconv.Atoi(match[1])
+		devices = append(devices, GPUDevice{ID: id, Name: strings.TrimSpace(match[2])})
+	}
+	if len(devices) == 0 && runErr != nil {
+		return nil, fmt.Errorf("无法列出显卡: %w", runErr)
+	}
+	return devices, nil
+}
+
+// checkGPUSetting 检查设置中的显卡编号是否都存在
+func checkGPUSetting(value string, devices []GPUDevice) error {
+	ids, err := parseGPUSetting(value)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id != -1 && !slices.ContainsFunc(devices, func(device GPUDevice) bool { return device.ID == id }) {
+			return fmt.Errorf("设置中的显卡 %d 不存在", id)
+		}
+	}
+	return nil
+}
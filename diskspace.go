@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	outputVideoBitrate = 15_000_000 // 合并时使用的视频码率（bps），与 -b:v 15M 对应
+	audioBitrateBound  = 320_000    // 估算音频时使用的码率上限（bps）
+	tempVideoBitsPerPx = 0.15       // minterpolate 前中间视频（x264 crf 18）每像素每帧的比特数
+
+	diskSafetyMargin = 0.1               // 可用空间低于预计用量的 110% 时给出警告
+	diskMinFree      = 512 * 1024 * 1024 // 处理过程中剩余空间低于该值则中止
+	diskPollInterval = 5 * time.Second
+)
+
+// diskEstimate 是一次处理任务的磁盘用量估算
+type diskEstimate struct {
+	Frames       int64   // 原始帧数
+	Ratio        float64 // 插帧输出帧数与原始帧数之比
+	ScratchBytes int64   // 工作目录峰值用量
+	OutputBytes  int64   // 最终输出文件大小
+	SegmentBytes int64   // 分段处理时所有片段的总大小
+
+	InFrameBytes  int64 // 单张拆帧图片的大小
+	OutFrameBytes int64 // 单张 RIFE 输出图片的大小
+	AudioBytes    int64
+}
+
+// estimateDiskUsage 根据分辨率、帧数和中间格式估算磁盘用量
+func estimateDiskUsage(width, height int, duration, fpsOrigin, fpsTarget, ratio float64, needFFMpegInterpolate bool, format frameFormat) diskEstimate {
+	pixels := float64(width * height)
+	frameBytes := pixels * format.BytesPerPixel()
+	frames := int64(duration*fpsOrigin + 0.5)
+	audioBytes := duration * audioBitrateBound / 8
+
+	// 拆出的帧 + RIFE 输出的 ratio 倍数量帧 + 音频
+	scratch := float64(frames)*frameBytes + float64(frames)*ratio*frameBytes + audioBytes
+
+	outputBytes := duration*outputVideoBitrate/8 + audioBytes
+	segmentBytes := outputBytes
+
+	// 非整数倍时还需要中间视频和 minterpolate 输出的帧
+	if needFFMpegInterpolate {
+		tempVideoBytes := duration * fpsOrigin * ratio * pixels * tempVideoBitsPerPx / 8
+		scratch += tempVideoBytes
+		scratch += duration * fpsTarget * frameBytes
+		segmentBytes = tempVideoBytes
+	}
+
+	return diskEstimate{
+		Frames:       frames,
+		Ratio:        ratio,
+		ScratchBytes: int64(scratch),
+		OutputBytes:  int64(outputBytes),
+		SegmentBytes: int64(segmentBytes),
+
+		InFrameBytes:  int64(frameBytes),
+		OutFrameBytes: int64(frameBytes),
+		AudioBytes:    int64(audioBytes),
+	}
+}
+
+// streamingScratch 返回流水线模式的峰值用量：全部拆帧图片加上同时存在的两个 RIFE 窗口
+func (e diskEstimate) streamingScratch(windowFrames int) int64 {
+	windows := 2 * int64(windowFrames+1) * (e.InFrameBytes + int64(e.Ratio*float64(e.OutFrameBytes)))
+	return min(e.ScratchBytes, e.Frames*e.InFrameBytes+windows+e.AudioBytes)
+}
+
+// chunkedScratch 返回分段处理时工作目录的峰值用量：单段帧文件加上已编码的片段
+func (e diskEstimate) chunkedScratch(limitBytes int64) int64 {
+	return min(e.ScratchBytes, limitBytes+e.SegmentBytes)
+}
+
+// checkDiskSpace 比较预计用量和工作目录、输出目录所在磁盘的可用空间
+// 空间不足时返回错误，余量较小时返回警告文本
+func checkDiskSpace(est diskEstimate, workRoot, outputDir string) (warning string, err error) {
+	type volumeNeed struct {
+		path string
+		name string
+		need int64
+	}
+
+	var needs []volumeNeed
+	if sameVolume(workRoot, outputDir) {
+		needs = append(needs, volumeNeed{workRoot, "工作目录和输出目录所在磁盘", est.ScratchBytes + est.OutputBytes})
+	} else {
+		needs = append(needs,
+			volumeNeed{workRoot, "工作目录所在磁盘", est.ScratchBytes},
+			volumeNeed{outputDir, "输出目录所在磁盘", est.OutputBytes},
+		)
+	}
+
+	for _, v := range needs {
+		free, err := diskFree(v.path)
+		if err != nil {
+			return "", fmt.Errorf("无法获取 %s 的可用空间: %w", v.path, err)
+		}
+		if uint64(v.need) > free {
+			return "", fmt.Errorf("%s空间不足：预计需要 %s，可用 %s（%s）",
+				v.name, formatBytes(v.need), formatBytes(int64(free)), v.path)
+		}
+		if float64(v.need)*(1+diskSafetyMargin) > float64(free) {
+			warning = fmt.Sprintf("%s剩余空间紧张：预计需要 %s，可用 %s", v.name, formatBytes(v.need), formatBytes(int64(free)))
+		}
+	}
+
+	return warning, nil
+}
+
+// monitorDiskSpace 在处理过程中定期检查磁盘剩余空间，
+// 低于 diskMinFree 时通过 cancel 中止任务，避免写满磁盘
+func monitorDiskSpace(ctx context.Context, cancel context.CancelCauseFunc, paths ...string) {
+	ticker := time.NewTicker(diskPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, path := range paths {
+				free, err := diskFree(path)
+				if err == nil && free < diskMinFree {
+					cancel(fmt.Errorf("磁盘空间不足：%s 仅剩 %s，已中止处理", path, formatBytes(int64(free))))
+					return
+				}
+			}
+		}
+	}
+}
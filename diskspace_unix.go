@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import "syscall"
+
+// diskFree 返回 path 所在磁盘对当前用户可用的字节数
+func diskFree(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
+
+// sameVolume 判断两个路径是否位于同一文件系统
+func sameVolume(a, b string) bool {
+	var statA, statB syscall.Stat_t
+	if syscall.Stat(a, &statA) != nil || syscall.Stat(b, &statB) != nil {
+		return false
+	}
+	return statA.Dev == statB.Dev
+}
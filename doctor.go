@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const doctorCommandTimeout = 15 * time.Second
+
+// 诊断时关注的编码器和滤镜
+var (
+	doctorEncoders = []string{"libx264", "h264_videotoolbox", "libopenh264", "png", "mjpeg", "libwebp"}
+	doctorFilters  = []string{"minterpolate", "framerate"}
+)
+
+var ffmpegVersionPattern = regexp.MustCompile(`version\s+(\S+)`)
+
+// ToolStatus 是单个依赖的诊断结果
+type ToolStatus struct {
+	Path    string           `json:"path"`
+	Source  DependencySource `json:"source"`
+	Version string           `json:"version,omitempty"`
+	OK      bool             `json:"ok"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// CapabilityReport 是依赖诊断报告，处理流程根据它选择编码器并提前发现缺失的功能
+type CapabilityReport struct {
+	FFmpeg       ToolStatus      `json:"ffmpeg"`
+	FFprobe      ToolStatus      `json:"ffprobe"`
+	Engine       string          `json:"engine"` // 插帧引擎名称
+	Interpolator ToolStatus      `json:"interpolator"`
+	Model        ToolStatus      `json:"model"`
+	GPUs         []GPUDevice     `json:"gpus"`
+	GPUError     string          `json:"gpu_error,omitempty"`
+	Encoders     map[string]bool `json:"encoders"`
+	Filters      map[string]bool `json:"filters"`
+
+	// 实际使用的插帧方式；AI 引擎不可用时回退到 ffmpeg 滤镜，Fallback 记录原因
+	Interpolation string `json:"interpolation"`
+	Fallback      string `json:"fallback,omitempty"`
+	interpolator  Interpolator
+}
+
+// runDoctor 实际运行每个依赖，检查版本、编码器、滤镜以及模型文件
+// 显卡列表在首次诊断时获取并缓存，之后的诊断只用缓存的列表检查设置中的显卡编号
+func runDoctor(ctx context.Context, depCheck *DependencyCheck) *CapabilityReport {
+	report := &CapabilityReport{
+		Engine:   depCheck.Engine.Name,
+		Encoders: map[string]bool{},
+		Filters:  map[string]bool{},
+	}
+
+	paths := depCheck.Paths
+	if paths == nil {
+		paths = &BinaryPaths{}
+	}
+	report.FFmpeg = ToolStatus{Path: paths.FFmpeg, Source: depCheck.Sources.FFmpeg}
+	report.FFprobe = ToolStatus{Path: paths.FFprobe, Source: depCheck.Sources.FFprobe}
+	report.Interpolator = ToolStatus{Path: paths.Interpolator, Source: depCheck.Sources.Interpolator}
+	report.Model = ToolStatus{Path: paths.Model, Source: depCheck.Sources.Model}
+
+	checkFFmpegTool(ctx, &report.FFmpeg)
+	checkFFmpegTool(ctx, &report.FFprobe)
+	checkInterpolator(ctx, depCheck.Engine, &report.Interpolator)
+	checkModel(depCheck.Engine, &report.Model)
+	if report.Interpolator.OK {
+		modelPath := ""
+		if report.Model.OK {
+			modelPath = report.Model.Path
+		}
+		if devices, err := cachedGPUDevices(ctx, report.Interpolator.Path, modelPath); err != nil {
+			report.GPUError = err.Error()
+		} else {
+			report.GPUs = devices
+		}
+	}
+
+	if report.FFmpeg.OK {
+		if output, err := runProbeCommand(ctx, report.FFmpeg.Path, "-hide_banner", "-encoders"); err == nil {
+			report.Encoders = parseFFmpegList(output, doctorEncoders)
+		}
+		if output, err := runProbeCommand(ctx, report.FFmpeg.Path, "-hide_banner", "-filters"); err == nil {
+			report.Filters = parseFFmpegList(output, doctorFilters)
+		}
+	}
+
+	report.interpolator, report.Fallback = chooseInterpolator(depCheck, report)
+	report.Interpolation = report.interpolator.ID()
+	return report
+}
+
+func (r *CapabilityReport) engineLabel() string {
+	if engine, ok := findEngine(r.Engine); ok {
+		return engine.Label
+	}
+	return r.Engine
+}
+
+// Problems 返回导致无法处理的问题列表
+// 插帧程序和模型的问题可以回退到 ffmpeg 滤镜插帧，由 Warnings 报告
+func (r *CapabilityReport) Problems() []string {
+	var problems []string
+	for _, tool := range []struct {
+		name   string
+		status ToolStatus
+	}{
+		{"FFmpeg", r.FFmpeg},
+		{"FFprobe", r.FFprobe},
+	} {
+		if !tool.status.OK {
+			problems = append(problems, fmt.Sprintf("%s: %s", tool.name, tool.status.Error))
+		}
+	}
+	if r.FFmpeg.OK && r.VideoCodec() == "" {
+		problems = append(problems, "FFmpeg 不支持任何可用的 H.264 编码器")
+	}
+	if backend, ok := r.interpolator.(*ffmpegInterpolator); ok && r.FFmpeg.OK && !r.Filters[backend.name] {
+		problems = append(problems, fmt.Sprintf("FFmpeg 不支持 %s 滤镜，无法插帧", backend.name))
+	}
+	return problems
+}
+
+// Warnings 返回不影响处理但会降低画质或速度的问题
+func (r *CapabilityReport) Warnings() []string {
+	if r.interpolator.LowQuality() {
+		if r.Fallback != "" {
+			return []string{r.Fallback, fmt.Sprintf("没有可用的 %s，将使用 %s插帧，画质明显低于 AI 插帧", r.engineLabel(), r.interpolator.Title())}
+		}
+		return []string{fmt.Sprintf("已选择 %s插帧，画质明显低于 AI 插帧", r.interpolator.Title())}
+	}
+
+	var warnings []string
+	switch {
+	case r.GPUError != "":
+		warnings = append(warnings, r.GPUError)
+	case len(r.GPUs) == 0 && strings.TrimSpace(appConfig.GPU) != "-1":
+		warnings = append(warnings, "没有检测到支持 Vulkan 的显卡，插帧会很慢或失败")
+	default:
+		if err := checkGPUSetting(appConfig.GPU, r.GPUs); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+	}
+	return warnings
+}
+
+// VideoCodec 根据平台和 FFmpeg 实际支持的编码器选择最终编码器
+// macOS 优先使用硬件编码 h264_videotoolbox，其余平台使用 libx264
+func (r *CapabilityReport) VideoCodec() string {
+	candidates := []string{"libx264", "libopenh264"}
+	if runtime.GOOS == "darwin" {
+		candidates = append([]string{"h264_videotoolbox"}, candidates...)
+	}
+	for _, codec := range candidates {
+		if r.Encoders[codec] {
+			return codec
+		}
+	}
+	return ""
+}
+
+// checkJobSupport 检查 FFmpeg 是否支持任务需要的编码器和滤镜
+func (r *CapabilityReport) checkJobSupport(format frameFormat, needFFMpegInterpolate bool) error {
+	if r.VideoCodec() == "" {
+		return fmt.Errorf("FFmpeg 不支持任何可用的 H.264 编码器（%s）", strings.Join(doctorEncoders[:3], "、"))
+	}
+	if format.Name == "webp" && !r.Encoders["libwebp"] {
+		return fmt.Errorf("FFmpeg 不支持 libwebp 编码器，请选择其他中间帧格式")
+	}
+	if needFFMpegInterpolate && !r.Filters["minterpolate"] {
+		return fmt.Errorf("FFmpeg 不支持 minterpolate 滤镜，无法转换到目标帧率")
+	}
+	return nil
+}
+
+// String 返回适合在终端或对话框中显示的报告
+func (r *CapabilityReport) String() string {
+	var b strings.Builder
+	for _, tool := range []struct {
+		name   string
+		status ToolStatus
+	}{
+		{"ffmpeg", r.FFmpeg},
+		{"ffprobe", r.FFprobe},
+		{r.Engine, r.Interpolator},
+		{"model", r.Model},
+	} {
+		mark := "✅"
+		if !tool.status.OK {
+			mark = "❌"
+		}
+		fmt.Fprintf(&b, "%s %s", mark, tool.name)
+		if tool.status.Version != "" {
+			fmt.Fprintf(&b, " %s", tool.status.Version)
+		}
+		path := tool.status.Path
+		if path == "" {
+			path = "-"
+		}
+		fmt.Fprintf(&b, "（%s）\n   %s\n", tool.status.Source, path)
+		if tool.status.Error != "" {
+			fmt.Fprintf(&b, "   %s\n", tool.status.Error)
+		}
+	}
+
+	b.WriteString("\n显卡:")
+	if len(r.GPUs) == 0 {
+		b.WriteString(" 未检测到")
+	}
+	for _, device := range r.GPUs {
+		fmt.Fprintf(&b, "\n   [%d] %s", device.ID, device.Name)
+	}
+	b.WriteString("\n编码器:")
+	for _, name := range doctorEncoders {
+		fmt.Fprintf(&b, " %s%s", name, availabilityMark(r.Encoders[name]))
+	}
+	b.WriteString("\n滤镜:")
+	for _, name := range doctorFilters {
+		fmt.Fprintf(&b, " %s%s", name, availabilityMark(r.Filters[name]))
+	}
+	if codec := r.VideoCodec(); codec != "" {
+		fmt.Fprintf(&b, "\n最终编码器: %s", codec)
+	}
+	fmt.Fprintf(&b, "\n插帧方式: %s\n", r.interpolator.Title())
+
+	if warnings := r.Warnings(); len(warnings) > 0 {
+		b.WriteString("\n提示:\n")
+		for _, warning := range warnings {
+			fmt.Fprintf(&b, "  - %s\n", warning)
+		}
+	}
+
+	if problems := r.Problems(); len(problems) > 0 {
+		b.WriteString("\n问题:\n")
+		for _, problem := range problems {
+			fmt.Fprintf(&b, "  - %s\n", problem)
+		}
+	}
+	return b.String()
+}
+
+func availabilityMark(ok bool) string {
+	if ok {
+		return "✅"
+	}
+	return "❌"
+}
+
+// checkFFmpegTool 运行 ffmpeg/ffprobe -version 并解析版本号
+func checkFFmpegTool(ctx context.Context, status *ToolStatus) {
+	if status.Path == "" {
+		status.Error = "未找到"
+		return
+	}
+	output, err := runProbeCommand(ctx, status.Path, "-version")
+	if err != nil {
+		status.Error = err.Error()
+		return
+	}
+
+	firstLine, _, _ := strings.Cut(output, "\n")
+	if match := ffmpegVersionPattern.FindStringSubmatch(firstLine); match != nil {
+		status.Version = match[1]
+	}
+	status.OK = true
+}
+
+// checkInterpolator 确认插帧程序能够启动
+// ncnn-vulkan 系列程序的 -h 打印用法后以非零状态退出，只要进程成功启动即可
+func checkInterpolator(ctx context.Context, engine *interpolationEngine, status *ToolStatus) {
+	if status.Path == "" {
+		status.Error = "未找到"
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, status.Path, "-h")
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		status.Error = fmt.Sprintf("无法启动: %v", err)
+		return
+	}
+	if ctx.Err() != nil {
+		status.Error = "启动超时"
+		return
+	}
+	if !strings.Contains(strings.ToLower(output.String()), "usage") {
+		status.Error = "输出中没有用法说明，可能不是 " + engine.Binary
+		return
+	}
+	status.OK = true
+}
+
+// checkModel 检查模型目录中的网络结构和权重文件
+func checkModel(engine *interpolationEngine, status *ToolStatus) {
+	if status.Path == "" {
+		status.Error = "未找到"
+		return
+	}
+	if err := validateModelDir(engine, status.Path); err != nil {
+		status.Error = err.Error()
+		return
+	}
+	status.Version = filepath.Base(status.Path)
+	status.OK = true
+}
+
+// runProbeCommand 运行诊断命令并返回标准输出，带超时
+func runProbeCommand(ctx context.Context, command string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, doctorCommandTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, command, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("运行失败: %w", err)
+	}
+	return string(output), nil
+}
+
+// parseFFmpegList 解析 ffmpeg -encoders / -filters 的输出，返回 names 中每一项是否存在
+// 两种输出的每一行都是“标志 名称 说明”的格式
+func parseFFmpegList(output string, names []string) map[string]bool {
+	found := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			found[fields[1]] = true
+		}
+	}
+
+	result := map[string]bool{}
+	for _, name := range names {
+		result[name] = found[name]
+	}
+	return result
+}
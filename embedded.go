@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const embeddedManifestFile = ".manifest.json"
+
+// embeddedFile 记录一个内嵌文件的路径（相对 binaries）、大小和校验和
+type embeddedFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// 内嵌文件在运行期间不会变化，清单只需计算一次
+var (
+	manifestOnce    sync.Once
+	manifestFiles   []embeddedFile
+	manifestVersion string
+	manifestErr     error
+)
+
+// embeddedManifest 列出内嵌的全部文件，版本号由所有文件的校验和计算得出
+// 需要读取并校验全部内嵌文件（包括 ffmpeg 和模型），结果在首次调用时缓存
+func embeddedManifest() ([]embeddedFile, string, error) {
+	manifestOnce.Do(func() {
+		manifestFiles, manifestVersion, manifestErr = computeEmbeddedManifest()
+	})
+	return manifestFiles, manifestVersion, manifestErr
+}
+
+func computeEmbeddedManifest() ([]embeddedFile, string, error) {
+	var files []embeddedFile
+	err := fs.WalkDir(binaries, "binaries", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := binaries.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		files = append(files, embeddedFile{
+			Path:   strings.TrimPrefix(name, "binaries/"),
+			Size:   int64(len(data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	h := sha256.New()
+	for _, file := range files {
+		fmt.Fprintf(h, "%s %s\n", file.Path, file.SHA256)
+	}
+	return files, hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// extractEmbeddedBinaries 将内嵌的 ffmpeg、ffprobe、RIFE 和模型文件解压到用户缓存目录
+// 缓存目录按内容版本区分，已解压且完整时直接复用
+func extractEmbeddedBinaries() (string, error) {
+	files, version, err := embeddedManifest()
+	if err != nil {
+		return "", fmt.Errorf("读取内嵌文件失败: %w", err)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("程序未内嵌依赖文件")
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	targetDir := filepath.Join(cacheDir, "fps2x", "binaries-"+version)
+
+	if embeddedCacheComplete(targetDir, files) {
+		return targetDir, nil
+	}
+
+	for _, file := range files {
+		if err := extractEmbeddedFile(targetDir, file); err != nil {
+			return "", err
+		}
+	}
+
+	manifest, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(targetDir, embeddedManifestFile), manifest, 0644); err != nil {
+		return "", fmt.Errorf("写入缓存清单失败: %w", err)
+	}
+
+	return targetDir, nil
+}
+
+// embeddedCacheComplete 检查缓存目录是否已完整解压（清单存在且文件大小一致）
+func embeddedCacheComplete(dir string, files []embeddedFile) bool {
+	if _, err := os.Stat(filepath.Join(dir, embeddedManifestFile)); err != nil {
+		return false
+	}
+	for _, file := range files {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		if err != nil || info.Size() != file.Size {
+			return false
+		}
+	}
+	return true
+}
+
+// extractEmbeddedFile 解压单个文件并校验 SHA-256
+// 先写入临时文件再重命名，避免多个实例同时解压时读到不完整的文件
+func extractEmbeddedFile(targetDir string, file embeddedFile) error {
+	data, err := binaries.ReadFile(path.Join("binaries", file.Path))
+	if err != nil {
+		return err
+	}
+
+	dst := filepath.Join(targetDir, filepath.FromSlash(file.Path))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+
+	// 顶层文件是可执行程序，模型目录中的是数据文件
+	mode := os.FileMode(0644)
+	if !strings.Contains(file.Path, "/") {
+		mode = 0755
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".extract-*")
+	if err != nil {
+		return fmt.Errorf("解压 %s 失败: %w", file.Path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("解压 %s 失败: %w", file.Path, err)
+	}
+
+	// 从磁盘读回校验，确认写入完整
+	sum, err := fileSHA256(tmp.Name())
+	if err != nil {
+		return err
+	}
+	if sum != file.SHA256 {
+		return fmt.Errorf("%s 校验失败：期望 %s，实际 %s", file.Path, file.SHA256, sum)
+	}
+
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("解压 %s 失败: %w", file.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// interpolationEngine 描述一个 ncnn-vulkan 命令行风格的插帧程序
+// 这些程序由同一作者维护，-i/-o/-m/-g/-j/-f 等参数含义一致，其余参数各不相同，由能力字段决定是否传入
+type interpolationEngine struct {
+	Name         string // 设置和命令行中使用的名称
+	Label        string // 界面和错误信息中显示的名称
+	Binary       string
+	EnvVar       string
+	DefaultModel string
+	ModelPrefix  string // 模型目录名前缀，用于发现模型
+
+	// 模型目录中必需的网络文件名（不含扩展名），为空时只要求每个 .param 都有对应的 .bin
+	NetworkFile string
+
+	// 支持的画质模式：-u UHD、-x 空间 TTA、-z 时间 TTA
+	HasUHD, HasSpatialTTA, HasTemporalTTA bool
+
+	// HasTileSize 表示支持 -t 分块大小，rife-ncnn-vulkan 和 ifrnet-ncnn-vulkan 没有这个选项
+	HasTileSize bool
+
+	// HasFrameCount 表示支持 -n 指定输出帧数，不支持时只能输出 2 倍帧
+	HasFrameCount bool
+
+	// 模型是否支持任意时间步（一次生成任意数量的中间帧）
+	supportsTimestep func(model string) bool
+
+	// autoThreads 按分辨率计算自动的 -j 线程数，为空时不传 -j，使用程序自身的默认值
+	autoThreads func(width, height int) (loadThreads, procThreads, saveThreads int)
+}
+
+const defaultEngineName = "rife"
+
+var engines = []*interpolationEngine{
+	{
+		Name:             "rife",
+		Label:            "RIFE",
+		Binary:           "rife-ncnn-vulkan",
+		EnvVar:           "FPS2X_RIFE",
+		DefaultModel:     "rife-v4.6",
+		ModelPrefix:      "rife-",
+		NetworkFile:      "flownet",
+		HasUHD:           true,
+		HasSpatialTTA:    true,
+		HasTemporalTTA:   true,
+		HasFrameCount:    true,
+		supportsTimestep: rifeSupportsTimestep,
+		autoThreads:      rifeThreads,
+	},
+	{
+		Name:             "ifrnet",
+		Label:            "IFRNet",
+		Binary:           "ifrnet-ncnn-vulkan",
+		EnvVar:           "FPS2X_IFRNET",
+		DefaultModel:     "IFRNet_Vimeo90K",
+		ModelPrefix:      "IFRNet",
+		HasUHD:           true,
+		HasSpatialTTA:    true,
+		HasTemporalTTA:   true,
+		HasFrameCount:    true,
+		supportsTimestep: func(string) bool { return true },
+	},
+	{
+		Name:             "cain",
+		Label:            "CAIN",
+		Binary:           "cain-ncnn-vulkan",
+		EnvVar:           "FPS2X_CAIN",
+		DefaultModel:     "cain",
+		ModelPrefix:      "cain",
+		HasSpatialTTA:    true,
+		HasTileSize:      true,
+		supportsTimestep: func(string) bool { return false }, // CAIN 只能生成中间一帧
+	},
+	{
+		Name:             "dain",
+		Label:            "DAIN",
+		Binary:           "dain-ncnn-vulkan",
+		EnvVar:           "FPS2X_DAIN",
+		DefaultModel:     "best",
+		ModelPrefix:      "best",
+		HasTileSize:      true,
+		HasFrameCount:    true,
+		supportsTimestep: func(string) bool { return true },
+	},
+}
+
+// engineNames 返回所有引擎名称，供界面选择和命令行帮助使用
+func engineNames() []string {
+	var names []string
+	for _, engine := range engines {
+		names = append(names, engine.Name)
+	}
+	return names
+}
+
+// findEngine 按名称查找引擎，名称不区分大小写
+func findEngine(name string) (*interpolationEngine, bool) {
+	for _, engine := range engines {
+		if strings.EqualFold(engine.Name, name) {
+			return engine, true
+		}
+	}
+	return nil, false
+}
+
+// engineAutoThreads 返回引擎的自动线程数策略，ffmpeg 滤镜等非 ncnn-vulkan 程序返回 nil
+func engineAutoThreads(name string) func(width, height int) (int, int, int) {
+	if engine, ok := findEngine(name); ok {
+		return engine.autoThreads
+	}
+	return nil
+}
+
+// engineHasTileSize 判断引擎是否支持 -t 分块大小，ffmpeg 滤镜等非 ncnn-vulkan 程序不支持
+func engineHasTileSize(name string) bool {
+	engine, ok := findEngine(name)
+	return ok && engine.HasTileSize
+}
+
+// engineForModel 根据模型目录名判断所属引擎
+func engineForModel(model string) (*interpolationEngine, bool) {
+	for _, engine := range engines {
+		if engine.ownsModel(model) {
+			return engine, true
+		}
+	}
+	return nil, false
+}
+
+func (e *interpolationEngine) ownsModel(model string) bool {
+	return strings.HasPrefix(model, e.ModelPrefix)
+}
+
+func (e *interpolationEngine) ID() string       { return e.Name }
+func (e *interpolationEngine) Title() string    { return e.Label }
+func (e *interpolationEngine) LowQuality() bool { return false }
+
+// Args 返回对 inDir 中的帧插帧的命令行参数，只包含该程序支持的选项
+// outputFrames 为 0 时沿用程序默认的 2 倍输出帧数，其他帧数需要 canSetFrameCount
+func (e *interpolationEngine) Args(job *videoJob, inDir, outDir string, outputFrames int64) []string {
+	tuning := job.tuning
+	args := []string{
+		"-i", inDir,
+		"-o", outDir,
+	}
+	if threads := tuning.ThreadsArg(); threads != "" {
+		args = append(args, "-j", threads)
+	}
+	args = append(args,
+		"-m", job.paths.Model,
+		"-f", job.format.Name,
+	)
+	if e.HasTileSize && tuning.TileSize > 0 {
+		args = append(args, "-t", tuning.TileArg())
+	}
+	if tuning.GPU != "" {
+		args = append(args, "-g", tuning.GPU)
+	}
+	args = append(args, job.modes.Args()...)
+	if outputFrames > 0 && e.HasFrameCount {
+		args = append(args, "-n", fmt.Sprintf("%d", outputFrames))
+	}
+	return args
+}
+
+// canSetFrameCount 判断能否用 model 一次生成任意数量的帧：程序要支持 -n，模型要支持任意时间步
+func (e *interpolationEngine) canSetFrameCount(model string) bool {
+	return e.HasFrameCount && e.supportsTimestep(model)
+}
+
+// SupportsRatio 能设置输出帧数时可以插到任意帧率；否则只能做 2 的幂倍插帧（多次 2 倍）
+func (e *interpolationEngine) SupportsRatio(model string, ratio frameRate) bool {
+	if ratio == (frameRate{2, 1}) || e.canSetFrameCount(model) {
+		return true
+	}
+	return ratio.Den == 1 && isPowerOfTwo(int(ratio.Num))
+}
+
+// Interpolate 用 ncnn-vulkan 插帧程序处理 inDir 中的帧
+// 非 2 倍时通过 -n 指定输出帧数，程序按输入帧数与输出帧数之比均匀计算每一帧的时间步
+func (e *interpolationEngine) Interpolate(ctx context.Context, job *videoJob, inDir, outDir string, ratio frameRate) error {
+	if ratio == (frameRate{2, 1}) {
+		return runCommand(ctx, job.paths.Interpolator, e.Args(job, inDir, outDir, 0))
+	}
+	model := filepath.Base(job.paths.Model)
+	if !e.canSetFrameCount(model) {
+		if !e.SupportsRatio(model, ratio) {
+			return fmt.Errorf("模型 %s 只支持 2 倍插帧，无法做 %s 倍插帧", model, ratio)
+		}
+		return e.interpolatePasses(ctx, job, inDir, outDir, int(ratio.Num))
+	}
+	return e.interpolateRatio(ctx, job, inDir, outDir, ratio)
+}
+
+// interpolateRatio 按分数比值插帧。插帧程序的时间步间隔为输入帧数与输出帧数之比，
+// 输入帧数不是分母的整数倍时用最后一帧补齐，使间隔恰好为 1/ratio，之后删除多出的输出帧
+func (e *interpolationEngine) interpolateRatio(ctx context.Context, job *videoJob, inDir, outDir string, ratio frameRate) error {
+	frames, err := countFiles(inDir)
+	if err != nil {
+		return err
+	}
+	_, last, err := job.format.FrameRange(inDir)
+	if err != nil {
+		return err
+	}
+	if frames == 0 || last == 0 {
+		return fmt.Errorf("没有可插帧的输入帧")
+	}
+
+	// 复制最后一帧补齐，编号接在目录中最大的编号之后（输入不一定从 1 开始编号），不覆盖已有的帧
+	padded := frames
+	if rem := frames % int(ratio.Den); rem != 0 {
+		padded += int(ratio.Den) - rem
+	}
+	for i := 1; i <= padded-frames; i++ {
+		pad := job.format.FramePath(inDir, last+i)
+		if err := linkOrCopy(job.format.FramePath(inDir, last), pad); err != nil {
+			return fmt.Errorf("补齐输入帧失败: %w", err)
+		}
+		defer os.Remove(pad)
+	}
+
+	if err := runCommand(ctx, job.paths.Interpolator, e.Args(job, inDir, outDir, ratio.scaleCount(int64(padded)))); err != nil {
+		return err
+	}
+	for i := ratio.scaleCount(int64(frames)) + 1; i <= ratio.scaleCount(int64(padded)); i++ {
+		os.Remove(job.format.FramePath(outDir, int(i)))
+	}
+	return nil
+}
+
+// interpolatePasses 重复做 2 倍插帧直到达到 multiplier 倍，中间结果写入 outDir 旁的临时目录
+// 每一轮都以上一轮的输出为输入，N 帧经过 k 轮后为 N×2^k 帧，与一次插帧的帧数一致
+func (e *interpolationEngine) interpolatePasses(ctx context.Context, job *videoJob, inDir, outDir string, multiplier int) error {
+	src := inDir
+	for pass, factor := 1, 2; factor <= multiplier; pass, factor = pass+1, factor*2 {
+		dst := outDir
+		if factor < multiplier {
+			dst = fmt.Sprintf("%s_x%d", outDir, factor)
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return fmt.Errorf("创建插帧中间目录失败: %w", err)
+			}
+		}
+		err := runCommand(ctx, job.paths.Interpolator, e.Args(job, src, dst, 0))
+		if src != inDir {
+			os.RemoveAll(src)
+		}
+		if err != nil {
+			if dst != outDir {
+				os.RemoveAll(dst)
+			}
+			return fmt.Errorf("第 %d 轮 2 倍插帧失败: %w", pass, err)
+		}
+		src = dst
+	}
+	return nil
+}
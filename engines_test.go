@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 设置该环境变量时测试程序扮演插帧程序，由 fakeInterpolator 处理命令行
+const fakeInterpolatorEnv = "FPS2X_TEST_FAKE_INTERPOLATOR"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeInterpolatorEnv) == "1" {
+		if err := fakeInterpolator(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeInterpolator 模拟 rife-ncnn-vulkan 的 -i/-o/-n/-f 参数：
+// 按文件名排序读取全部输入帧，第 k 张输出帧（从 0 开始）位于输入帧 k×N/n 处，
+// 内容记录所在的输入帧内容和小数部分，便于测试检查时间步
+func fakeInterpolator(args []string) error {
+	var inDir, outDir, format string
+	outputFrames := 0
+	for i := 0; i+1 < len(args); i += 2 {
+		switch args[i] {
+		case "-i":
+			inDir = args[i+1]
+		case "-o":
+			outDir = args[i+1]
+		case "-f":
+			format = args[i+1]
+		case "-n":
+			outputFrames, _ = strconv.Atoi(args[i+1])
+		}
+	}
+	entries, err := os.ReadDir(inDir)
+	if err != nil {
+		return err
+	}
+	var inputs []string
+	for _, entry := range entries {
+		inputs = append(inputs, entry.Name())
+	}
+	slices.Sort(inputs)
+	n := len(inputs)
+	if outputFrames == 0 {
+		outputFrames = 2 * n
+	}
+	for k := range outputFrames {
+		source, err := os.ReadFile(filepath.Join(inDir, inputs[k*n/outputFrames]))
+		if err != nil {
+			return err
+		}
+		frac := big.NewRat(int64(k*n%outputFrames), int64(outputFrames))
+		data := fmt.Sprintf("%s@%s", source, frac.RatString())
+		if err := os.WriteFile(filepath.Join(outDir, fmt.Sprintf("%08d.%s", k+1, format)), []byte(data), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newFakeInterpolatorJob 返回使用 fakeInterpolator 做 RIFE v4 插帧的任务
+func newFakeInterpolatorJob(t *testing.T, ratio frameRate) *videoJob {
+	t.Helper()
+	t.Setenv(fakeInterpolatorEnv, "1")
+	engine, _ := findEngine("rife")
+	return &videoJob{
+		paths:        &BinaryPaths{Interpolator: os.Args[0], Model: "rife-v4.6"},
+		interpolator: engine,
+		workDir:      t.TempDir(),
+		ui:           &consoleReporter{name: "test", out: io.Discard, mu: &sync.Mutex{}},
+		format:       newFrameFormat("png", 0),
+		ratio:        ratio,
+	}
+}
+
+// checkOutputFrames 检查 outDir 的第 1..count 张输出帧依次位于输入帧 first 之后 (start+k)/ratio 处，k 从 0 开始
+func checkOutputFrames(t *testing.T, format frameFormat, outDir string, count, first, start int, ratio frameRate) {
+	t.Helper()
+	for k := range count {
+		data, err := os.ReadFile(format.FramePath(outDir, k+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pos := (start + k) * int(ratio.Den)
+		want := fmt.Sprintf("%d@%s", first+pos/int(ratio.Num), big.NewRat(int64(pos%int(ratio.Num)), ratio.Num).RatString())
+		if string(data) != want {
+			t.Fatalf("第 %d 张输出帧为 %s，want %s", start+k+1, data, want)
+		}
+	}
+}
+
+func TestInterpolateRatioWindow(t *testing.T) {
+	// 流水线第二个窗口的输入为 00000101..00000201，101 帧需要补齐到 2 的倍数
+	ratio := frameRate{5, 2}
+	job := newFakeInterpolatorJob(t, ratio)
+	inDir := filepath.Join(job.workDir, "in")
+	outDir := filepath.Join(job.workDir, "out")
+	writeInputFrames(t, job.format, inDir, 101, 201)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := job.interpolate(context.Background(), inDir, outDir); err != nil {
+		t.Fatal(err)
+	}
+
+	// 101 帧补齐为 102 帧后输出 255 帧，保留 ceil(101×5/2) = 253 帧
+	outputs, err := countFiles(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outputs != 253 {
+		t.Errorf("输出 %d 帧，want 253", outputs)
+	}
+	checkOutputFrames(t, job.format, outDir, 253, 101, 0, ratio)
+
+	// 补齐的帧已删除，原有的输入帧没有被覆盖
+	first, last, err := job.format.FrameRange(inDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != 101 || last != 201 {
+		t.Errorf("输入帧编号为 %d..%d，want 101..201", first, last)
+	}
+	for i := 101; i <= 201; i++ {
+		data, err := os.ReadFile(job.format.FramePath(inDir, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != strconv.Itoa(i) {
+			t.Fatalf("输入帧 %d 被覆盖为 %s", i, data)
+		}
+	}
+}
+
+func TestInterpolateWindows(t *testing.T) {
+	tests := []struct {
+		name   string
+		ratio  frameRate
+		frames int
+	}{
+		{"24 到 60 帧", frameRate{5, 2}, 250},
+		{"29.97 到 60 帧", frameRate{1001, 500}, 1200},
+		{"2 倍", frameRate{2, 1}, 201},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := newFakeInterpolatorJob(t, tt.ratio)
+			job.frames = int64(tt.frames)
+			writeInputFrames(t, job.format, filepath.Join(job.workDir, "in"), 1, tt.frames)
+
+			extractDone := make(chan error, 1)
+			extractDone <- nil
+			ready := make(chan streamWindow)
+			errc := make(chan error, 1)
+			go func() {
+				defer close(ready)
+				errc <- interpolateWindows(context.Background(), job, extractDone, ready)
+			}()
+
+			// 各窗口保留的输出帧拼接后与整段一次插帧的结果一致
+			written := 0
+			for window := range ready {
+				checkOutputFrames(t, job.format, filepath.Join(window.dir, "out"), window.frames, 1, written, tt.ratio)
+				written += window.frames
+			}
+			if err := <-errc; err != nil {
+				t.Fatal(err)
+			}
+			if want := int(tt.ratio.scaleCount(int64(tt.frames))); written != want {
+				t.Errorf("共输出 %d 帧，want %d", written, want)
+			}
+			if leftover, _ := filepath.Glob(filepath.Join(job.workDir, "in", "*")); len(leftover) > 0 {
+				t.Errorf("拆帧目录中遗留 %s", strings.Join(leftover, ", "))
+			}
+		})
+	}
+}
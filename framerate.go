@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// frameRate 是用分数表示的帧率，如 NTSC 的 30000/1001
+// 全程使用分数传给 ffmpeg，避免 29.97 被取整为 30 导致音画逐渐不同步
+type frameRate struct {
+	Num, Den int64
+}
+
+func newFrameRate(num, den int64) frameRate {
+	if num <= 0 || den <= 0 {
+		return frameRate{}
+	}
+	g := gcd(num, den)
+	return frameRate{num / g, den / g}
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// parseFrameRate 解析 30000/1001、30 或 29.97 形式的帧率
+// 小数形式与 NTSC 帧率（N*1000/1001）相差不到 0.01 时按 NTSC 帧率处理
+func parseFrameRate(text string) (frameRate, error) {
+	text = strings.TrimSpace(text)
+	invalid := fmt.Errorf("无效的帧率: %q", text)
+	if numerator, denominator, found := strings.Cut(text, "/"); found {
+		num, err1 := strconv.ParseInt(numerator, 10, 64)
+		den, err2 := strconv.ParseInt(denominator, 10, 64)
+		if err1 != nil || err2 != nil || num <= 0 || den <= 0 {
+			return frameRate{}, invalid
+		}
+		return newFrameRate(num, den), nil
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return frameRate{}, invalid
+	}
+	if value == math.Trunc(value) {
+		return newFrameRate(int64(value), 1), nil
+	}
+	if ntsc := math.Round(value * 1001 / 1000); math.Abs(ntsc*1000/1001-value) < 0.01 {
+		return newFrameRate(int64(ntsc)*1000, 1001), nil
+	}
+	return newFrameRate(int64(math.Round(value*1000)), 1000), nil
+}
+
+func (r frameRate) IsZero() bool {
+	return r.Num <= 0 || r.Den <= 0
+}
+
+func (r frameRate) Float() float64 {
+	if r.IsZero() {
+		return 0
+	}
+	return float64(r.Num) / float64(r.Den)
+}
+
+// Mul 返回帧率的整数倍
+func (r frameRate) Mul(n int64) frameRate {
+	return newFrameRate(r.Num*n, r.Den)
+}
+
+// Ratio 返回 r 与 other 的比值
+func (r frameRate) Ratio(other frameRate) frameRate {
+	return newFrameRate(r.Num*other.Den, r.Den*other.Num)
+}
+
+// Scale 返回帧率乘以比值 ratio 的结果
+func (r frameRate) Scale(ratio frameRate) frameRate {
+	return newFrameRate(r.Num*ratio.Num, r.Den*ratio.Den)
+}
+
+// scaleCount 把帧数 n 按比值换算，用于插帧前后的帧数，不能整除时向上取整
+func (r frameRate) scaleCount(n int64) int64 {
+	return (n*r.Num + r.Den - 1) / r.Den
+}
+
+// alignDown 把帧数向下对齐到分母的整数倍（至少一个分母），使分段后每段的插帧结果恰好为整数帧
+func (r frameRate) alignDown(n int) int {
+	den := int(r.Den)
+	return max(n-n%den, den)
+}
+
+// String 返回传给 ffmpeg 的形式，整数帧率不带分母
+func (r frameRate) String() string {
+	if r.Den == 1 {
+		return strconv.FormatInt(r.Num, 10)
+	}
+	return fmt.Sprintf("%d/%d", r.Num, r.Den)
+}
+
+// Label 返回用于显示和文件名的形式，最多保留三位小数，如 23.976、29.97、60
+func (r frameRate) Label() string {
+	return strconv.FormatFloat(math.Round(r.Float()*1000)/1000, 'f', -1, 64)
+}
+
+func (r frameRate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *frameRate) UnmarshalText(text []byte) error {
+	parsed, err := parseFrameRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// isNTSC 判断是否为 NTSC 系列帧率（分母为 1001）
+func (r frameRate) isNTSC() bool {
+	return r.Den == 1001
+}
+
+// outputModeOption 是可选的输出帧率模式
+// Target 为零表示按 Multiplier 倍输出，否则输出固定的 Target 帧率
+type outputModeOption struct {
+	Name       string
+	Label      string
+	Multiplier int
+	Target     frameRate
+}
+
+const (
+	maxMultiplier = 8 // 不能直接插到目标帧率时多次 2 倍插帧的倍数上限
+
+	// 直接插到目标帧率时比值分母的上限。NTSC 帧率到固定帧率模式的分母最大为 1250（59.94 到 144 帧为 3003/1250），
+	// 只有 NTSC 帧率到 165 帧和 59.94 到 90 帧超过上限
+	maxRatioDenominator = 1250
+
+	// 分段和流水线窗口至少包含的分母个数。每段输入帧补齐到分母的整数倍，补齐的帧少于一个分母，
+	// 因此额外的插帧量不超过 1/minDenominatorsPerUnit
+	minDenominatorsPerUnit = 4
+)
+
+var outputModes = []outputModeOption{
+	{Name: "2x", Label: "2倍帧率（高质量）", Multiplier: 2},
+	{Name: "3x", Label: "3倍帧率", Multiplier: 3},
+	{Name: "4x", Label: "4倍帧率", Multiplier: 4},
+	{Name: "8x", Label: "8倍帧率（慢动作素材）", Multiplier: 8},
+	{Name: "48fps", Label: "固定48帧（电影高帧率）", Target: frameRate{48, 1}},
+	{Name: "50fps", Label: "固定50帧（PAL）", Target: frameRate{50, 1}},
+	{Name: "59.94fps", Label: "固定59.94帧（NTSC）", Target: frameRate{60000, 1001}},
+	{Name: "60fps", Label: "固定60帧（通用）", Target: frameRate{60, 1}},
+	{Name: "90fps", Label: "固定90帧", Target: frameRate{90, 1}},
+	{Name: "120fps", Label: "固定120帧（高刷新率屏幕）", Target: frameRate{120, 1}},
+	{Name: "144fps", Label: "固定144帧（电竞显示器）", Target: frameRate{144, 1}},
+	{Name: "165fps", Label: "固定165帧", Target: frameRate{165, 1}},
+	{Name: "240fps", Label: "固定240帧", Target: frameRate{240, 1}},
+}
+
+func findOutputMode(name string) (outputModeOption, bool) {
+	for _, mode := range outputModes {
+		if mode.Name == name || mode.Label == name {
+			return mode, true
+		}
+	}
+	return outputModeOption{}, false
+}
+
+func outputModeNames() []string {
+	var names []string
+	for _, mode := range outputModes {
+		names = append(names, mode.Name)
+	}
+	return names
+}
+
+func outputModeLabels() []string {
+	var labels []string
+	for _, mode := range outputModes {
+		labels = append(labels, mode.Label)
+	}
+	return labels
+}
+
+// outputModeLabel 返回模式在界面上的名称，未知模式按帧率翻倍处理
+func outputModeLabel(name string) string {
+	if mode, ok := findOutputMode(name); ok {
+		return mode.Label
+	}
+	return outputModes[0].Label
+}
+
+// targetFrameRate 返回模式对应的目标帧率
+func (m outputModeOption) targetFrameRate(origin frameRate) frameRate {
+	if m.Target.IsZero() {
+		return origin.Mul(int64(m.Multiplier))
+	}
+	return m.Target
+}
+
+// directRatio 返回固定帧率模式下目标帧率与原始帧率之比，支持任意时间步的模型可以直接按该比值插帧
+// 比值不大于 1 时返回零，此时需要先整数倍插帧再由 FFmpeg 补帧；分母是否超过上限由调用方检查并提示
+func (m outputModeOption) directRatio(origin frameRate) frameRate {
+	if m.Target.IsZero() {
+		return frameRate{}
+	}
+	ratio := m.Target.Ratio(origin)
+	if ratio.Num <= ratio.Den {
+		return frameRate{}
+	}
+	return ratio
+}
+
+// fallbackMultiplier 返回不能直接插到目标帧率时的整数倍数，之后由 FFmpeg 补充到目标帧率：
+// 取 2 的幂（最多 maxMultiplier 倍），使插帧后的帧率不低于目标帧率的 90%，如 29.97 到 60 帧仍为 2 倍
+func (m outputModeOption) fallbackMultiplier(origin frameRate) int {
+	if m.Target.IsZero() {
+		return m.Multiplier
+	}
+	multiplier := 2
+	for multiplier < maxMultiplier && origin.Mul(int64(multiplier)).Float() < 0.9*m.Target.Float() {
+		multiplier *= 2
+	}
+	return multiplier
+}
+
+// isPowerOfTwo 判断倍数能否由多次 2 倍插帧得到
+func isPowerOfTwo(n int) bool {
+	return n >= 2 && n&(n-1) == 0
+}
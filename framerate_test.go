@@ -0,0 +1,172 @@
+package main
+
+import "testing"
+
+func TestParseFrameRate(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    frameRate
+		wantErr bool
+	}{
+		{text: "30", want: frameRate{30, 1}},
+		{text: " 60 ", want: frameRate{60, 1}},
+		{text: "30000/1001", want: frameRate{30000, 1001}},
+		{text: "24000/1001", want: frameRate{24000, 1001}},
+		{text: "60/2", want: frameRate{30, 1}},
+		{text: "29.97", want: frameRate{30000, 1001}},
+		{text: "23.976", want: frameRate{24000, 1001}},
+		{text: "59.94", want: frameRate{60000, 1001}},
+		{text: "12.5", want: frameRate{25, 2}},
+		{text: "0", wantErr: true},
+		{text: "-30", wantErr: true},
+		{text: "30/0", wantErr: true},
+		{text: "abc", wantErr: true},
+		{text: "", wantErr: true},
+		{text: "NaN", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseFrameRate(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFrameRate(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseFrameRate(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFrameRateRatioAndScale(t *testing.T) {
+	tests := []struct {
+		name           string
+		origin, target frameRate
+		ratio          frameRate
+	}{
+		{"29.97 到 59.94", frameRate{30000, 1001}, frameRate{60000, 1001}, frameRate{2, 1}},
+		{"23.976 到 60", frameRate{24000, 1001}, frameRate{60, 1}, frameRate{1001, 400}},
+		{"24 到 60", frameRate{24, 1}, frameRate{60, 1}, frameRate{5, 2}},
+		{"29.97 到 60", frameRate{30000, 1001}, frameRate{60, 1}, frameRate{1001, 500}},
+		{"59.94 到 144", frameRate{60000, 1001}, frameRate{144, 1}, frameRate{3003, 1250}},
+		{"25 到 50", frameRate{25, 1}, frameRate{50, 1}, frameRate{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ratio := tt.target.Ratio(tt.origin)
+			if ratio != tt.ratio {
+				t.Errorf("Ratio() = %v, want %v", ratio, tt.ratio)
+			}
+			// 按比值换算回来必须恰好是目标帧率，不能因取整产生误差
+			if got := tt.origin.Scale(ratio); got != tt.target {
+				t.Errorf("Scale(%v) = %v, want %v", ratio, got, tt.target)
+			}
+		})
+	}
+}
+
+func TestScaleCount(t *testing.T) {
+	tests := []struct {
+		ratio frameRate
+		n     int64
+		want  int64
+	}{
+		{frameRate{2, 1}, 100, 200},
+		{frameRate{5, 2}, 100, 250},
+		{frameRate{5, 2}, 101, 253}, // 252.5 向上取整
+		{frameRate{1001, 400}, 400, 1001},
+		{frameRate{1001, 400}, 1, 3},
+		{frameRate{1001, 500}, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.ratio.scaleCount(tt.n); got != tt.want {
+			t.Errorf("%v.scaleCount(%d) = %d, want %d", tt.ratio, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestAlignDown(t *testing.T) {
+	tests := []struct {
+		ratio frameRate
+		n     int
+		want  int
+	}{
+		{frameRate{2, 1}, 100, 100},
+		{frameRate{5, 2}, 100, 100},
+		{frameRate{5, 2}, 101, 100},
+		{frameRate{12, 5}, 99, 95},
+		{frameRate{1001, 400}, 1000, 800},
+		{frameRate{1001, 500}, 100, 500}, // 至少一个分母
+	}
+	for _, tt := range tests {
+		if got := tt.ratio.alignDown(tt.n); got != tt.want {
+			t.Errorf("%v.alignDown(%d) = %d, want %d", tt.ratio, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestDirectRatio(t *testing.T) {
+	tests := []struct {
+		mode   string
+		origin frameRate
+		want   frameRate
+	}{
+		{"59.94fps", frameRate{30000, 1001}, frameRate{2, 1}},
+		{"60fps", frameRate{24000, 1001}, frameRate{1001, 400}},
+		{"60fps", frameRate{24, 1}, frameRate{5, 2}},
+		{"60fps", frameRate{30000, 1001}, frameRate{1001, 500}},
+		{"120fps", frameRate{60000, 1001}, frameRate{1001, 500}},
+		{"144fps", frameRate{60000, 1001}, frameRate{3003, 1250}},
+		{"60fps", frameRate{60, 1}, frameRate{}},    // 不高于原始帧率
+		{"48fps", frameRate{60, 1}, frameRate{}},    // 低于原始帧率
+		{"2x", frameRate{30000, 1001}, frameRate{}}, // 倍数模式
+	}
+	for _, tt := range tests {
+		mode, ok := findOutputMode(tt.mode)
+		if !ok {
+			t.Fatalf("找不到输出模式 %s", tt.mode)
+		}
+		if got := mode.directRatio(tt.origin); got != tt.want {
+			t.Errorf("%s.directRatio(%v) = %v, want %v", tt.mode, tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestFallbackMultiplier(t *testing.T) {
+	tests := []struct {
+		mode   string
+		origin frameRate
+		want   int
+	}{
+		{"3x", frameRate{30, 1}, 3},
+		{"8x", frameRate{24, 1}, 8},
+		{"60fps", frameRate{30000, 1001}, 2}, // 59.94 不低于 60 的 90%
+		{"60fps", frameRate{24000, 1001}, 4},
+		{"60fps", frameRate{24, 1}, 4},
+		{"120fps", frameRate{30, 1}, 4},
+		{"240fps", frameRate{24, 1}, 8}, // 最多 8 倍
+		{"48fps", frameRate{60, 1}, 2},
+	}
+	for _, tt := range tests {
+		mode, ok := findOutputMode(tt.mode)
+		if !ok {
+			t.Fatalf("找不到输出模式 %s", tt.mode)
+		}
+		if got := mode.fallbackMultiplier(tt.origin); got != tt.want {
+			t.Errorf("%s.fallbackMultiplier(%v) = %d, want %d", tt.mode, tt.origin, got, tt.want)
+		}
+	}
+}
+
+// 固定帧率模式中只有 NTSC 帧率到 165 帧和 59.94 到 90 帧的比值分母超过上限
+func TestRatioDenominatorLimit(t *testing.T) {
+	origins := []frameRate{{24000, 1001}, {24, 1}, {25, 1}, {30000, 1001}, {30, 1}, {50, 1}, {60000, 1001}, {60, 1}}
+	for _, mode := range outputModes {
+		for _, origin := range origins {
+			ratio := mode.directRatio(origin)
+			exceeded := ratio.Den > maxRatioDenominator
+			want := origin.isNTSC() && (mode.Name == "165fps" || (mode.Name == "90fps" && origin == frameRate{60000, 1001}))
+			if exceeded != want {
+				t.Errorf("%s 从 %s 帧的比值 %v 超过分母上限: %v, want %v", mode.Name, origin.Label(), ratio, exceeded, want)
+			}
+		}
+	}
+}
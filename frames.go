@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 可选的中间帧格式，默认 JPG
+var frameFormatNames = []string{"jpg", "png", "webp"}
+
+// frameFormat 是拆帧、RIFE 输出和合并时统一使用的中间帧格式
+// JPG（默认，与早期版本的拆帧格式一致）和 WebP 有损，Quality 为 1-100；PNG 无损但体积大，需要手动选择
+type frameFormat struct {
+	Name    string
+	Quality int
+}
+
+const defaultFrameQuality = 100 // 对应早期版本拆帧使用的 -q:v 2
+
+func newFrameFormat(name string, quality int) frameFormat {
+	switch name {
+	case "png", "webp":
+	default:
+		name = "jpg"
+	}
+	if quality < 1 || quality > 100 {
+		quality = defaultFrameQuality
+	}
+	return frameFormat{Name: name, Quality: quality}
+}
+
+// Pattern 返回 ffmpeg 使用的帧序列路径模板
+func (f frameFormat) Pattern(dir string) string {
+	return filepath.Join(dir, "%08d."+f.Name)
+}
+
+// FramePath 返回第 index 帧（从 1 开始）的路径
+func (f frameFormat) FramePath(dir string, index int) string {
+	return filepath.Join(dir, fmt.Sprintf("%08d.%s", index, f.Name))
+}
+
+// FrameRange 返回 dir 中帧编号的最小值和最大值，没有帧时都为 0
+// 分块和流水线窗口的输入帧不一定从 1 开始编号
+func (f frameFormat) FrameRange(dir string) (first, last int, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), "."+f.Name)
+		if entry.IsDir() || !ok {
+			continue
+		}
+		index, err := strconv.Atoi(name)
+		if err != nil || index < 1 {
+			continue
+		}
+		if first == 0 || index < first {
+			first = index
+		}
+		last = max(last, index)
+	}
+	return first, last, nil
+}
+
+// EncodeArgs 返回 ffmpeg 输出该格式图片时的编码参数
+func (f frameFormat) EncodeArgs() []string {
+	switch f.Name {
+	case "jpg":
+		// 质量 100 对应 -q:v 2，质量 1 对应 -q:v 31
+		qscale := 2 + int(math.Round(float64(100-f.Quality)*29/99))
+		return []string{"-q:v", fmt.Sprintf("%d", qscale)}
+	case "webp":
+		return []string{"-c:v", "libwebp", "-quality", fmt.Sprintf("%d", f.Quality)}
+	default:
+		return nil
+	}
+}
+
+// PipeCodec 返回 image2pipe 读取该格式时使用的解码器
+func (f frameFormat) PipeCodec() string {
+	switch f.Name {
+	case "jpg":
+		return "mjpeg"
+	default:
+		return f.Name
+	}
+}
+
+// BytesPerPixel 返回该格式每像素的平均字节数（经验值），用于估算磁盘用量
+func (f frameFormat) BytesPerPixel() float64 {
+	switch f.Name {
+	case "jpg":
+		return 0.3 * (0.4 + 0.6*float64(f.Quality)/100)
+	case "webp":
+		return 0.2 * (0.4 + 0.6*float64(f.Quality)/100)
+	default:
+		return 1.8
+	}
+}
+
+func (f frameFormat) String() string {
+	if f.Name == "png" {
+		return "PNG（无损）"
+	}
+	return fmt.Sprintf("%s（质量 %d）", map[string]string{"jpg": "JPG", "webp": "WebP"}[f.Name], f.Quality)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Interpolator 把 inDir 中的帧序列插帧为 ratio 倍帧率，以任务的中间帧格式写入 outDir
+// ratio 可以是整数倍或分数（如 24 到 60 帧为 5/2），N 张输入帧输出 N×ratio 张（向上取整），
+// 与 rife-ncnn-vulkan 的行为一致（末尾补足重复帧）
+type Interpolator interface {
+	ID() string    // 设置和命令行中使用的名称
+	Title() string // 界面和日志中显示的名称
+	// LowQuality 为 true 表示不使用 AI 模型，画质明显低于 RIFE，界面上需要提示
+	LowQuality() bool
+	// SupportsRatio 判断能否用 model 做 ratio 倍插帧，model 为模型目录名
+	SupportsRatio(model string, ratio frameRate) bool
+	Interpolate(ctx context.Context, job *videoJob, inDir, outDir string, ratio frameRate) error
+}
+
+// ffmpegInterpolator 使用 ffmpeg 自带的滤镜插帧，不需要 Vulkan 和模型文件
+type ffmpegInterpolator struct {
+	name   string
+	label  string
+	filter string // 滤镜模板，%s 为目标帧率
+}
+
+var ffmpegInterpolators = []*ffmpegInterpolator{
+	{
+		name:   "minterpolate",
+		label:  "FFmpeg 运动补偿（minterpolate）",
+		filter: "minterpolate=fps=%s:mi_mode=mci:mc_mode=aobmc:me_mode=bidir_ref:vsbmc=1",
+	},
+	{
+		name:   "framerate",
+		label:  "FFmpeg 帧混合（framerate）",
+		filter: "framerate=fps=%s",
+	},
+}
+
+func (f *ffmpegInterpolator) ID() string       { return f.name }
+func (f *ffmpegInterpolator) Title() string    { return f.label }
+func (f *ffmpegInterpolator) LowQuality() bool { return true }
+
+func (f *ffmpegInterpolator) SupportsRatio(string, frameRate) bool { return true }
+
+func (f *ffmpegInterpolator) Interpolate(ctx context.Context, job *videoJob, inDir, outDir string, ratio frameRate) error {
+	frames, err := countFiles(inDir)
+	if err != nil {
+		return err
+	}
+	first, _, err := job.format.FrameRange(inDir)
+	if err != nil {
+		return err
+	}
+	if frames == 0 || first == 0 {
+		return fmt.Errorf("没有可插帧的输入帧")
+	}
+
+	// 输入帧从目录中最小的编号开始读取（image2 默认只在 0-4 中查找起始编号），输出从 1 开始编号
+	// 末尾复制一帧，使最后一张输入帧之后也能生成插值帧，输出帧数与 RIFE 一致
+	// 帧序列没有时间戳，输入帧率只用于换算，取原始帧率便于阅读日志
+	rate := job.fpsOrigin.String()
+	target := job.fpsOrigin.Scale(ratio).String()
+	args := []string{
+		"-y",
+		"-framerate", rate,
+		"-start_number", strconv.Itoa(first),
+		"-i", job.format.Pattern(inDir),
+		"-vf", "tpad=stop_mode=clone:stop=1," + fmt.Sprintf(f.filter, target),
+		"-frames:v", fmt.Sprintf("%d", ratio.scaleCount(int64(frames))),
+	}
+	args = append(args, job.format.EncodeArgs()...)
+	return runCommand(ctx, job.paths.FFmpeg, append(args, job.format.Pattern(outDir)))
+}
+
+// interpolatorNames 返回所有可选的插帧方式：ncnn-vulkan 引擎在前，ffmpeg 滤镜在后
+func interpolatorNames() []string {
+	names := engineNames()
+	for _, backend := range ffmpegInterpolators {
+		names = append(names, backend.name)
+	}
+	return names
+}
+
+func findFFmpegInterpolator(name string) (*ffmpegInterpolator, bool) {
+	for _, backend := range ffmpegInterpolators {
+		if backend.name == name {
+			return backend, true
+		}
+	}
+	return nil, false
+}
+
+// fallbackInterpolator 在没有可用的 AI 插帧程序时选择 ffmpeg 滤镜
+// 优先使用画质较好的 minterpolate，ffmpeg 不支持时使用 framerate 帧混合
+func fallbackInterpolator(report *CapabilityReport) *ffmpegInterpolator {
+	if report == nil || report.Filters["minterpolate"] || !report.Filters["framerate"] {
+		return ffmpegInterpolators[0]
+	}
+	return ffmpegInterpolators[1]
+}
+
+// chooseInterpolator 根据依赖检查和诊断报告确定任务实际使用的插帧方式，并返回回退的原因
+// 设置的 AI 引擎或模型找不到、无法启动时回退到 ffmpeg 滤镜；用户直接选择 ffmpeg 滤镜时不算回退
+func chooseInterpolator(depCheck *DependencyCheck, report *CapabilityReport) (Interpolator, string) {
+	if depCheck.Fallback != "" {
+		return fallbackInterpolator(report), depCheck.Fallback
+	}
+	if depCheck.Backend != nil {
+		return depCheck.Backend, ""
+	}
+	if !report.Interpolator.OK {
+		return fallbackInterpolator(report), fmt.Sprintf("%s: %s", depCheck.Engine.Label, report.Interpolator.Error)
+	}
+	if !report.Model.OK {
+		return fallbackInterpolator(report), fmt.Sprintf("%s 模型: %s", depCheck.Engine.Label, report.Model.Error)
+	}
+	return depCheck.Engine, ""
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"image/color"
+	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -49,7 +52,16 @@ var (
 	stepMergeProgress   *widget.ProgressBar
 
 	// 模式选择
-	outputMode       string // "2x" 或 "60fps"
+	outputMode string // "2x"、"3x" 等倍数或 "60fps"、"59.94fps" 等固定帧率，见 outputModes
+
+	// 设置
+	workDirLabel   *widget.Label
+	modelSelect    *widget.Select
+	modelInfoLabel *widget.Label
+	presetSelect   *widget.Select
+
+	// 启动后在后台发现的当前引擎的模型
+	availableModels []EngineModel
 )
 
 type ProcessingStep int
@@ -62,19 +74,36 @@ const (
 )
 
 type DependencyCheck struct {
-	Ready  bool
-	Paths  *BinaryPaths
-	Error  string
+	Ready   bool
+	Paths   *BinaryPaths
+	Sources BinarySources
+	Engine  *interpolationEngine // 设置中选择的插帧引擎
+	Error   string
+
+	// 设置中选择或回退使用的 ffmpeg 滤镜插帧方式，为空时使用 Engine
+	Backend  *ffmpegInterpolator
+	Fallback string // 回退到 ffmpeg 滤镜的原因
 }
 
 type BinaryPaths struct {
-	FFmpeg  string
-	FFprobe string
-	RIFE    string
-	Model   string
+	FFmpeg       string
+	FFprobe      string
+	Interpolator string // 插帧程序（rife-ncnn-vulkan 等）
+	Model        string
 }
 
 func main() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	appConfig = cfg
+
+	// 带子命令时以命令行模式运行
+	if args := os.Args[1:]; isCLIInvocation(args) {
+		os.Exit(runCLI(args))
+	}
+
 	myApp := app.NewWithID("com.fps2x.desktop")
 
 	// 初始化默认模式
@@ -88,8 +117,13 @@ func main() {
 	ui := createUI()
 	mainWindow.SetContent(ui)
 
-	// 启动时检查依赖
-	go checkDependenciesOnStart()
+	// 启动时检查依赖，清理上次遗留的临时输出并扫描遗留工作目录
+	go func() {
+		checkDependenciesOnStart()
+		cleanupOutputsOnStart()
+		scanOrphanWorkDirsOnStart()
+		promptBenchmarkOnStart()
+	}()
 
 	mainWindow.ShowAndRun()
 }
@@ -128,20 +162,308 @@ func createUI() *fyne.Container {
 	modeTitle := widget.NewLabel("输出帧率模式")
 	modeTitle.TextStyle = fyne.TextStyle{Bold: true}
 
-	modeSelect := widget.NewRadioGroup([]string{"2倍帧率（高质量）", "固定60帧（通用）"}, func(s string) {
-		if s == "2倍帧率（高质量）" {
-			outputMode = "2x"
-		} else {
-			outputMode = "60fps"
+	// 倍数模式和常见显示器刷新率较多，使用下拉框
+	modeSelect := widget.NewSelect(outputModeLabels(), func(s string) {
+		if mode, ok := findOutputMode(s); ok {
+			outputMode = mode.Name
 		}
 	})
-	modeSelect.Horizontal = true // 横向排列
-	modeSelect.Selected = "2倍帧率（高质量）" // 默认选中第一个
+	modeSelect.Selected = outputModes[0].Label // 默认选中第一个
 
 	modeBox := container.NewVBox(
 		modeSelect,
 	)
 
+	// 工作目录设置
+	settingsTitle := widget.NewLabel("工作目录")
+	settingsTitle.TextStyle = fyne.TextStyle{Bold: true}
+
+	workDirLabel = widget.NewLabel("")
+	workDirLabel.Wrapping = fyne.TextWrapWord
+	refreshWorkDirLabel()
+
+	workDirButtons := []fyne.CanvasObject{
+		widget.NewButton("更改", onSelectWorkDir),
+		widget.NewButton("恢复默认", func() { setWorkDir("") }),
+	}
+	// Linux 上 /dev/shm 是内存盘，适合存放短视频的临时帧
+	if runtime.GOOS == "linux" {
+		if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+			workDirButtons = append(workDirButtons, widget.NewButton("内存盘", func() { setWorkDir("/dev/shm") }))
+		}
+	}
+	workDirButtons = append(workDirButtons, widget.NewButton("清理遗留文件", onCleanupWorkDirs))
+
+	// 临时空间上限，超过时分段处理
+	scratchLimitEntry := widget.NewEntry()
+	scratchLimitEntry.SetPlaceHolder("0 表示不限制")
+	if appConfig.ScratchLimitGB > 0 {
+		scratchLimitEntry.SetText(strconv.FormatFloat(appConfig.ScratchLimitGB, 'f', -1, 64))
+	}
+	scratchLimitEntry.OnChanged = func(text string) {
+		limit, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if text != "" && (err != nil || limit < 0) {
+			return
+		}
+		appConfig.ScratchLimitGB = limit
+		saveConfig(appConfig)
+	}
+
+	pipelineCheck := widget.NewCheck("流水线处理（拆帧、插帧、编码同时进行）", func(checked bool) {
+		appConfig.Pipeline = checked
+		saveConfig(appConfig)
+	})
+	pipelineCheck.SetChecked(appConfig.Pipeline)
+
+	parallelCheck := widget.NewCheck("多进程并行拆帧与编码（libx264 编码时生效）", func(checked bool) {
+		appConfig.ParallelSegments = checked
+		saveConfig(appConfig)
+	})
+	parallelCheck.SetChecked(appConfig.ParallelSegments)
+
+	// 中间帧格式和质量
+	qualityEntry := widget.NewEntry()
+	qualityEntry.SetText(strconv.Itoa(appConfig.frameFormat().Quality))
+	qualityEntry.OnChanged = func(text string) {
+		quality, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil || quality < 1 || quality > 100 {
+			return
+		}
+		appConfig.FrameQuality = quality
+		saveConfig(appConfig)
+	}
+	formatSelect := widget.NewSelect(frameFormatNames, func(name string) {
+		appConfig.FrameFormat = name
+		saveConfig(appConfig)
+		if name == "png" {
+			qualityEntry.Disable()
+		} else {
+			qualityEntry.Enable()
+		}
+	})
+	formatSelect.SetSelected(appConfig.frameFormat().Name)
+
+	// 后台优先级，长时间处理时不影响其他程序
+	backgroundCheck := widget.NewCheck("后台优先级（降低 CPU 和 I/O 优先级）", func(checked bool) {
+		appConfig.BackgroundPriority = checked
+		saveConfig(appConfig)
+	})
+	backgroundCheck.SetChecked(appConfig.BackgroundPriority)
+	ffmpegThreadsEntry := widget.NewEntry()
+	ffmpegThreadsEntry.SetPlaceHolder("不限制")
+	if appConfig.FFmpegThreads > 0 {
+		ffmpegThreadsEntry.SetText(strconv.Itoa(appConfig.FFmpegThreads))
+	}
+	ffmpegThreadsEntry.OnChanged = func(text string) {
+		threads, err := strconv.Atoi(strings.TrimSpace(text))
+		if text != "" && (err != nil || threads < 0) {
+			return
+		}
+		appConfig.FFmpegThreads = threads
+		saveConfig(appConfig)
+	}
+
+	// 同时处理多个任务时的资源槽位和磁盘预算，留空表示默认值
+	slotEntry := func(value, fallback int, set func(int)) *widget.Entry {
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder(strconv.Itoa(fallback))
+		if value > 0 {
+			entry.SetText(strconv.Itoa(value))
+		}
+		entry.OnChanged = func(text string) {
+			slots, err := strconv.Atoi(strings.TrimSpace(text))
+			if text != "" && (err != nil || slots < 0) {
+				return
+			}
+			set(slots)
+			saveConfig(appConfig)
+		}
+		return entry
+	}
+	gpuSlotsEntry := slotEntry(appConfig.GPUSlots, defaultGPUSlots, func(n int) { appConfig.GPUSlots = n })
+	encodeSlotsEntry := slotEntry(appConfig.EncodeSlots, defaultEncodeSlots, func(n int) { appConfig.EncodeSlots = n })
+	diskBudgetEntry := widget.NewEntry()
+	diskBudgetEntry.SetPlaceHolder("不限制")
+	if appConfig.DiskBudgetGB > 0 {
+		diskBudgetEntry.SetText(strconv.FormatFloat(appConfig.DiskBudgetGB, 'f', -1, 64))
+	}
+	diskBudgetEntry.OnChanged = func(text string) {
+		budget, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if text != "" && (err != nil || budget < 0) {
+			return
+		}
+		appConfig.DiskBudgetGB = budget
+		saveConfig(appConfig)
+	}
+
+	// 可变帧率视频的处理方式，帧率留空表示按平均帧率自动选择
+	vfrModeSelect := widget.NewSelect(vfrModeLabels, func(label string) {
+		appConfig.VFRMode = vfrModeValues[max(slices.Index(vfrModeLabels, label), 0)]
+		saveConfig(appConfig)
+	})
+	vfrModeSelect.SetSelected(vfrModeLabels[max(slices.Index(vfrModeValues, appConfig.VFRSettings.mode()), 0)])
+	vfrRateEntry := widget.NewEntry()
+	vfrRateEntry.SetPlaceHolder("auto，如 30 或 30000/1001")
+	vfrRateEntry.SetText(appConfig.VFRRate)
+	vfrRateEntry.OnChanged = func(text string) {
+		settings := appConfig.VFRSettings
+		settings.VFRRate = strings.TrimSpace(text)
+		if settings.validate() != nil {
+			return
+		}
+		appConfig.VFRSettings = settings
+		saveConfig(appConfig)
+	}
+
+	// 插帧程序高级参数，留空表示自动；输入无效时不保存，并在这一行末尾显示原因
+	tuningErrorLabel := widget.NewLabel("")
+	tuningErrorLabel.Importance = widget.DangerImportance
+	applyEngineTuning := func(tuning EngineTuning) {
+		if err := setEngineTuning(tuning); err != nil {
+			tuningErrorLabel.SetText("❌ " + err.Error())
+			return
+		}
+		tuningErrorLabel.SetText("")
+	}
+	threadsEntry := widget.NewEntry()
+	threadsEntry.SetPlaceHolder("auto")
+	threadsEntry.SetText(appConfig.Threads)
+	threadsEntry.OnChanged = func(text string) {
+		tuning := appConfig.EngineTuning
+		tuning.Threads = strings.TrimSpace(text)
+		applyEngineTuning(tuning)
+	}
+	tileEntry := widget.NewEntry()
+	tileEntry.SetPlaceHolder("auto")
+	if appConfig.TileSize > 0 {
+		tileEntry.SetText(strconv.Itoa(appConfig.TileSize))
+	}
+	tileEntry.OnChanged = func(text string) {
+		tuning := appConfig.EngineTuning
+		tuning.TileSize = 0
+		if text = strings.TrimSpace(text); text != "" && !isAutoValue(text) {
+			size, err := strconv.Atoi(text)
+			if err != nil {
+				tuningErrorLabel.SetText("❌ 分块大小必须是 32 的倍数: " + text)
+				return
+			}
+			tuning.TileSize = size
+		}
+		applyEngineTuning(tuning)
+	}
+	// RIFE 和 IFRNet 没有 -t 选项，选择这些引擎时禁用分块大小
+	updateTileEntry := func() {
+		if engineHasTileSize(appConfig.interpolatorName()) {
+			tileEntry.Enable()
+		} else {
+			tileEntry.Disable()
+		}
+	}
+	updateTileEntry()
+	gpuEntry := widget.NewEntry()
+	gpuEntry.SetPlaceHolder("auto，如 0,1")
+	gpuEntry.SetText(appConfig.GPU)
+	gpuEntry.OnChanged = func(text string) {
+		tuning := appConfig.EngineTuning
+		tuning.GPU = strings.TrimSpace(text)
+		applyEngineTuning(tuning)
+	}
+
+	// 画质模式：UHD 默认在 4K 时自动开启，TTA 画质更好但明显更慢
+	uhdSelect := widget.NewSelect(uhdModeLabels, func(label string) {
+		appConfig.UHD = uhdModeValues[slices.Index(uhdModeLabels, label)]
+		if appConfig.UHD == "auto" {
+			appConfig.UHD = ""
+		}
+		saveConfig(appConfig)
+	})
+	spatialTTACheck := widget.NewCheck(fmt.Sprintf("空间 TTA（约 %.0f 倍耗时）", spatialTTACost), func(checked bool) {
+		appConfig.SpatialTTA = checked
+		saveConfig(appConfig)
+	})
+	temporalTTACheck := widget.NewCheck(fmt.Sprintf("时间 TTA（约 %.0f 倍耗时）", temporalTTACost), func(checked bool) {
+		appConfig.TemporalTTA = checked
+		saveConfig(appConfig)
+	})
+	setQualityModeWidgets := func() {
+		uhd := appConfig.UHD
+		if isAutoValue(uhd) {
+			uhd = "auto"
+		}
+		uhdSelect.SetSelected(uhdModeLabels[max(slices.Index(uhdModeValues, uhd), 0)])
+		spatialTTACheck.SetChecked(appConfig.SpatialTTA)
+		temporalTTACheck.SetChecked(appConfig.TemporalTTA)
+	}
+	setQualityModeWidgets()
+
+	// 插帧引擎，切换后重新查找该引擎的模型；ffmpeg 滤镜不使用模型
+	engineSelect := widget.NewSelect(interpolatorNames(), func(name string) {
+		if name == appConfig.interpolatorName() {
+			return
+		}
+		appConfig.Engine = name
+		saveConfig(appConfig)
+		updateTileEntry()
+		go refreshModelList()
+	})
+	engineSelect.SetSelected(appConfig.interpolatorName())
+
+	// 模型，可选项在启动后由 refreshModelList 填充
+	modelInfoLabel = widget.NewLabel("")
+	modelSelect = widget.NewSelect([]string{appConfig.modelName()}, func(name string) {
+		if name != appConfig.modelName() {
+			appConfig.Model = name
+			saveConfig(appConfig)
+		}
+		updateModelInfo()
+	})
+	modelSelect.SetSelected(appConfig.modelName())
+
+	// 预设：一键切换输出模式、模型、中间帧格式和流水线设置
+	presetSelect = widget.NewSelect(appConfig.presetNames(), func(name string) {
+		preset, ok := appConfig.findPreset(name)
+		if !ok {
+			return
+		}
+		appConfig.applyPreset(preset)
+		saveConfig(appConfig)
+		engineSelect.SetSelected(appConfig.interpolatorName())
+		go refreshModelList()
+
+		modeSelect.SetSelected(outputModeLabel(preset.Mode))
+		setModelOptions()
+		formatSelect.SetSelected(appConfig.frameFormat().Name)
+		qualityEntry.SetText(strconv.Itoa(appConfig.frameFormat().Quality))
+		pipelineCheck.SetChecked(appConfig.Pipeline)
+		setQualityModeWidgets()
+		threadsEntry.SetText(appConfig.Threads)
+		gpuEntry.SetText(appConfig.GPU)
+		if appConfig.TileSize > 0 {
+			tileEntry.SetText(strconv.Itoa(appConfig.TileSize))
+		} else {
+			tileEntry.SetText("")
+		}
+		updateTileEntry()
+	})
+	presetSelect.PlaceHolder = "选择预设"
+
+	settingsBox := container.NewVBox(
+		container.NewHBox(widget.NewLabel("预设"), presetSelect, widget.NewButton("保存为预设", onSavePreset), widget.NewButton("删除预设", onDeletePreset)),
+		container.NewHBox(widget.NewLabel("插帧引擎"), engineSelect, widget.NewLabel("模型"), modelSelect, widget.NewButton("管理模型", onManageModels), modelInfoLabel),
+		container.NewHBox(widget.NewLabel("中间帧格式"), formatSelect, widget.NewLabel("质量（1-100）"), qualityEntry),
+		container.NewHBox(widget.NewLabel("画质：UHD"), uhdSelect, spatialTTACheck, temporalTTACheck),
+		container.NewHBox(widget.NewLabel("可变帧率"), vfrModeSelect, widget.NewLabel("固定帧率"), vfrRateEntry),
+		container.NewHBox(widget.NewLabel("高级：线程（-j）"), threadsEntry, widget.NewLabel("分块（-t）"), tileEntry, widget.NewLabel("显卡（-g，-1 为 CPU）"), gpuEntry, tuningErrorLabel),
+		pipelineCheck,
+		parallelCheck,
+		container.NewHBox(backgroundCheck, widget.NewLabel("ffmpeg 线程上限"), ffmpegThreadsEntry),
+		container.NewHBox(widget.NewLabel("并发：插帧槽位"), gpuSlotsEntry, widget.NewLabel("编码槽位"), encodeSlotsEntry, widget.NewLabel("磁盘预算（GB）"), diskBudgetEntry),
+		workDirLabel,
+		container.NewHBox(workDirButtons...),
+		container.NewBorder(nil, nil, widget.NewLabel("临时空间上限（GB）"), nil, scratchLimitEntry),
+		container.NewHBox(widget.NewButton("依赖诊断", onShowDiagnostics), widget.NewButton("性能测试", onBenchmark)),
+	)
+
 	// 按钮区域
 	selectBtn = widget.NewButton("选择视频文件", onSelectFile)
 	selectBtnCentered := container.NewCenter(selectBtn)
@@ -205,6 +527,11 @@ func createUI() *fyne.Container {
 		container.NewPadded(modeBox),
 		widget.NewSeparator(),
 
+		// 设置区域
+		container.NewPadded(settingsTitle),
+		container.NewPadded(settingsBox),
+		widget.NewSeparator(),
+
 		// 进度区域
 		container.NewPadded(progressLabel),
 		container.NewPadded(progressBar),
@@ -231,6 +558,430 @@ func createStepLabel(icon, text string) *widget.Label {
 	return label
 }
 
+func refreshWorkDirLabel() {
+	if appConfig.WorkDir == "" {
+		workDirLabel.SetText("默认（与输出目录相同）")
+	} else {
+		workDirLabel.SetText(appConfig.WorkDir)
+	}
+}
+
+func setWorkDir(path string) {
+	appConfig.WorkDir = path
+	if err := saveConfig(appConfig); err != nil {
+		dialog.ShowError(err, mainWindow)
+	}
+	refreshWorkDirLabel()
+}
+
+func onSelectWorkDir() {
+	fd := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+		if err != nil {
+			dialog.ShowError(err, mainWindow)
+			return
+		}
+		if uri == nil {
+			return
+		}
+		setWorkDir(uri.Path())
+	}, mainWindow)
+	fd.Show()
+}
+
+// onCleanupWorkDirs 列出遗留的工作目录及其大小，确认后删除
+func onCleanupWorkDirs() {
+	orphans, err := findOrphanWorkDirs()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("扫描工作目录失败: %w", err), mainWindow)
+		return
+	}
+	if len(orphans) == 0 {
+		dialog.ShowInformation("清理遗留文件", "没有遗留的工作目录", mainWindow)
+		return
+	}
+
+	var lines []string
+	var total int64
+	for _, dir := range orphans {
+		lines = append(lines, fmt.Sprintf("%s  %s", formatBytes(dir.Size), dir.Path))
+		total += dir.Size
+	}
+	message := fmt.Sprintf("%s\n\n共 %d 个目录，%s。是否删除？", strings.Join(lines, "\n"), len(orphans), formatBytes(total))
+
+	dialog.ShowConfirm("清理遗留文件", message, func(ok bool) {
+		if !ok {
+			return
+		}
+		go func() {
+			freed, err := removeWorkDirs(orphans)
+			fyne.Do(func() {
+				if err != nil {
+					dialog.ShowError(err, mainWindow)
+					return
+				}
+				statusLabel.SetText(fmt.Sprintf("已清理遗留工作目录，释放 %s", formatBytes(freed)))
+			})
+		}()
+	}, mainWindow)
+}
+
+// setEngineTuning 校验并保存插帧程序高级参数，输入无效时不修改设置
+func setEngineTuning(tuning EngineTuning) error {
+	if err := tuning.validate(); err != nil {
+		return err
+	}
+	appConfig.EngineTuning = tuning
+	if err := saveConfig(appConfig); err != nil {
+		return fmt.Errorf("保存设置失败: %w", err)
+	}
+	return nil
+}
+
+// refreshModelList 查找所有 rife-* 模型并更新模型选择框
+func refreshModelList() {
+	depCheck, err := checkDependencies()
+	if err != nil {
+		return
+	}
+	models := discoverModels(depCheck.Engine, depCheck.Paths.Interpolator, &bundledDir{})
+	fyne.Do(func() {
+		availableModels = models
+		setModelOptions()
+	})
+}
+
+// setModelOptions 用可用模型更新选择框，当前选择的模型即使无效也保留在选项中
+func setModelOptions() {
+	options := validModelNames(availableModels)
+	current := appConfig.modelName()
+	if !slices.Contains(options, current) {
+		options = append(options, current)
+	}
+	modelSelect.SetOptions(options)
+	modelSelect.SetSelected(current)
+	if _, ok := appConfig.ffmpegBackend(); ok {
+		modelSelect.Disable()
+	} else {
+		modelSelect.Enable()
+	}
+	updateModelInfo()
+}
+
+func updateModelInfo() {
+	if _, ok := appConfig.ffmpegBackend(); ok {
+		modelInfoLabel.SetText("⚠️ 不使用 AI 模型，画质较低")
+		return
+	}
+	model, ok := findModel(availableModels, appConfig.modelName())
+	switch {
+	case !ok:
+		modelInfoLabel.SetText("未找到")
+	case !model.Valid():
+		modelInfoLabel.SetText("❌ " + model.Error)
+	case model.ArbitraryTimestep:
+		modelInfoLabel.SetText("支持任意时间步")
+	default:
+		modelInfoLabel.SetText("仅支持 2、4、8 倍插帧（多次 2 倍）")
+	}
+}
+
+// onManageModels 显示所有模型的版本、大小和来源，可导入新模型或删除导入的模型
+func onManageModels() {
+	var d dialog.Dialog
+
+	rows := container.NewVBox()
+	if len(availableModels) == 0 {
+		rows.Add(widget.NewLabel("没有找到 rife-* 模型"))
+	}
+	for _, model := range availableModels {
+		info := fmt.Sprintf("%s  %s  %s", model.Name, formatBytes(model.Size), model.Source)
+		if !model.ImportedAt.IsZero() {
+			info += "  导入于 " + model.ImportedAt.Local().Format("2006-01-02 15:04")
+		}
+		if !model.Valid() {
+			info += "\n❌ " + model.Error
+		}
+		label := widget.NewLabel(info)
+		label.Wrapping = fyne.TextWrapWord
+
+		if model.Source != SourceUser {
+			rows.Add(label)
+			continue
+		}
+		name := model.Name
+		rows.Add(container.NewBorder(nil, nil, nil, widget.NewButton("删除", func() {
+			d.Hide()
+			dialog.ShowConfirm("删除模型", fmt.Sprintf("删除导入的模型“%s”？", name), func(ok bool) {
+				if !ok {
+					return
+				}
+				if err := removeModel(name); err != nil {
+					dialog.ShowError(err, mainWindow)
+					return
+				}
+				statusLabel.SetText(fmt.Sprintf("已删除模型 %s", name))
+				go refreshModelList()
+			}, mainWindow)
+		}), label))
+	}
+
+	importArchive := widget.NewButton("导入压缩包", func() {
+		d.Hide()
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, mainWindow)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			reader.Close()
+			importModelFrom(reader.URI().Path())
+		}, mainWindow)
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".zip", ".gz", ".tgz"}))
+		fd.Show()
+	})
+	importFolder := widget.NewButton("导入文件夹", func() {
+		d.Hide()
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, mainWindow)
+				return
+			}
+			if uri == nil {
+				return
+			}
+			importModelFrom(uri.Path())
+		}, mainWindow)
+	})
+
+	scroll := container.NewVScroll(rows)
+	scroll.SetMinSize(fyne.NewSize(560, 300))
+	d = dialog.NewCustom("管理模型", "关闭", container.NewBorder(nil, container.NewHBox(importArchive, importFolder), nil, nil, scroll), mainWindow)
+	d.Show()
+}
+
+// importModelFrom 在后台导入模型，完成后刷新模型列表
+func importModelFrom(path string) {
+	statusLabel.SetText("正在导入模型...")
+	go func() {
+		models, err := importModel(path)
+		if err != nil {
+			showError(fmt.Sprintf("导入模型失败: %v", err))
+			return
+		}
+		refreshModelList()
+
+		var names []string
+		for _, model := range models {
+			names = append(names, model.Name)
+		}
+		fyne.Do(func() {
+			statusLabel.SetText(fmt.Sprintf("已导入模型: %s", strings.Join(names, "、")))
+		})
+	}()
+}
+
+// onSavePreset 将当前设置保存为用户预设
+func onSavePreset() {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(presetSelect.Selected)
+	dialog.ShowForm("保存为预设", "保存", "取消", []*widget.FormItem{
+		widget.NewFormItem("名称", nameEntry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		name := strings.TrimSpace(nameEntry.Text)
+		if err := appConfig.savePreset(appConfig.currentPreset(name, outputMode)); err != nil {
+			dialog.ShowError(err, mainWindow)
+			return
+		}
+		saveConfig(appConfig)
+		presetSelect.SetOptions(appConfig.presetNames())
+		presetSelect.SetSelected(name)
+	}, mainWindow)
+}
+
+// onDeletePreset 删除当前选中的用户预设
+func onDeletePreset() {
+	name := presetSelect.Selected
+	if name == "" {
+		return
+	}
+	dialog.ShowConfirm("删除预设", fmt.Sprintf("删除预设“%s”？", name), func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := appConfig.deletePreset(name); err != nil {
+			dialog.ShowError(err, mainWindow)
+			return
+		}
+		saveConfig(appConfig)
+		presetSelect.ClearSelected()
+		presetSelect.SetOptions(appConfig.presetNames())
+	}, mainWindow)
+}
+
+// onShowDiagnostics 在后台运行依赖诊断，完成后显示报告
+func onShowDiagnostics() {
+	statusLabel.SetText("正在诊断依赖...")
+	go func() {
+		depCheck, err := checkDependencies()
+		if err != nil {
+			showError(fmt.Sprintf("依赖检查失败: %v", err))
+			return
+		}
+		report := runDoctor(context.Background(), depCheck)
+
+		fyne.Do(func() {
+			if problems := report.Problems(); len(problems) > 0 {
+				statusLabel.SetText(fmt.Sprintf("诊断发现 %d 个问题", len(problems)))
+			} else {
+				statusLabel.SetText("诊断完成，所有依赖正常")
+			}
+
+			reportLabel := widget.NewLabel(report.String())
+			reportLabel.Wrapping = fyne.TextWrapWord
+			scroll := container.NewVScroll(reportLabel)
+			scroll.SetMinSize(fyne.NewSize(560, 360))
+			dialog.ShowCustom("依赖诊断", "关闭", scroll, mainWindow)
+		})
+	}()
+}
+
+// onBenchmark 显示性能测试向导：选择分辨率和测试片段，逐个测试线程和分块组合并保存最快的组合
+func onBenchmark() {
+	bucketCheck := widget.NewCheckGroup(resolutionBucketNames(), nil)
+	bucketCheck.Horizontal = true
+	bucketCheck.SetSelected(resolutionBucketNames())
+	tileCheck := widget.NewCheck("同时测试分块大小 256 和 512（耗时约为三倍）", nil)
+	if !engineHasTileSize(appConfig.interpolatorName()) {
+		tileCheck.Hide() // 当前插帧程序没有 -t 选项
+	}
+
+	clipPath := ""
+	clipLabel := widget.NewLabel("测试片段: ffmpeg 生成的测试画面")
+	clipButton := widget.NewButton("选择测试片段（可选）", func() {
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			reader.Close()
+			clipPath = reader.URI().Path()
+			clipLabel.SetText("测试片段: " + filepath.Base(clipPath))
+		}, mainWindow)
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".mp4", ".avi", ".mov", ".mkv", ".wmv", ".flv"}))
+		fd.Show()
+	})
+
+	progress := widget.NewProgressBar()
+	resultLabel := widget.NewLabel("")
+	resultLabel.Wrapping = fyne.TextWrapWord
+	scroll := container.NewVScroll(resultLabel)
+	scroll.SetMinSize(fyne.NewSize(560, 240))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var startButton *widget.Button
+	startButton = widget.NewButton("开始测试", func() {
+		opts := benchmarkOptions{Clip: clipPath, Frames: defaultBenchmarkFrames, Tiles: []int{0}}
+		for _, name := range bucketCheck.Selected {
+			if bucket, ok := findResolutionBucket(name); ok {
+				opts.Buckets = append(opts.Buckets, bucket)
+			}
+		}
+		if len(opts.Buckets) == 0 {
+			return
+		}
+		if tileCheck.Checked {
+			opts.Tiles = append(opts.Tiles, 256, 512)
+		}
+		startButton.Disable()
+		resultLabel.SetText("")
+		go runBenchmarkWizard(ctx, opts, progress, resultLabel, startButton)
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("在本机上测试不同的线程数和分块大小，之后处理视频时自动使用最快的组合。\n设置中手动指定的高级参数优先于测试结果。"),
+			bucketCheck,
+			tileCheck,
+			container.NewHBox(clipButton, clipLabel),
+			startButton,
+			progress,
+		),
+		nil, nil, nil, scroll,
+	)
+	d := dialog.NewCustom("性能测试", "关闭", content, mainWindow)
+	d.SetOnClosed(cancel) // 关闭窗口时中止正在运行的测试
+	d.Show()
+}
+
+// runBenchmarkWizard 在后台运行性能测试，逐条显示结果，完成后保存最快的组合
+func runBenchmarkWizard(ctx context.Context, opts benchmarkOptions, progress *widget.ProgressBar, resultLabel *widget.Label, startButton *widget.Button) {
+	defer fyne.Do(startButton.Enable)
+
+	appendResult := func(text string) {
+		fyne.Do(func() {
+			resultLabel.SetText(strings.TrimPrefix(resultLabel.Text+"\n"+text, "\n"))
+		})
+	}
+
+	depCheck, err := checkDependencies()
+	if err != nil {
+		appendResult(fmt.Sprintf("依赖检查失败: %v", err))
+		return
+	}
+	if !depCheck.Ready {
+		appendResult("依赖错误: " + depCheck.Error)
+		return
+	}
+
+	profiles, _, err := runBenchmark(ctx, depCheck, opts, func(done, total int, result benchmarkResult) {
+		fyne.Do(func() { progress.SetValue(float64(done) / float64(total)) })
+		appendResult(result.String())
+	})
+	if err != nil {
+		appendResult(fmt.Sprintf("测试中止: %v", err))
+		return
+	}
+	if len(profiles) == 0 {
+		appendResult("所有组合都失败，没有保存结果")
+		return
+	}
+
+	fyne.Do(func() {
+		lines := []string{"", "已保存最快的组合:"}
+		for _, profile := range profiles {
+			appConfig.saveTuningProfile(profile)
+			lines = append(lines, profile.String())
+		}
+		appConfig.BenchmarkPrompted = true
+		saveConfig(appConfig)
+		resultLabel.SetText(resultLabel.Text + strings.Join(lines, "\n"))
+	})
+}
+
+// promptBenchmarkOnStart 首次找到可用的 AI 插帧程序时询问是否运行性能测试，只询问一次
+func promptBenchmarkOnStart() {
+	if appConfig.BenchmarkPrompted || len(appConfig.TuningProfiles) > 0 {
+		return
+	}
+	depCheck, err := checkDependencies()
+	if err != nil || !depCheck.Ready || depCheck.Backend != nil {
+		return
+	}
+	fyne.Do(func() {
+		dialog.ShowConfirm("性能测试", "首次使用建议在本机上测试不同的插帧线程数和分块大小，\n之后处理视频时会自动使用最快的组合。是否现在测试？", func(ok bool) {
+			appConfig.BenchmarkPrompted = true
+			saveConfig(appConfig)
+			if ok {
+				onBenchmark()
+			}
+		}, mainWindow)
+	})
+}
+
 func onSelectFile() {
 	fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
 		if err != nil {
@@ -282,7 +1033,13 @@ func onProcessVideo() {
 	resetSteps()
 
 	// 在后台处理视频
-	go processVideo(selectedFilePath)
+	go func() {
+		defer fyne.Do(func() {
+			selectBtn.Enable()
+			processBtn.Enable()
+		})
+		processVideo(selectedFilePath, outputMode, guiReporter{})
+	}()
 }
 
 func checkDependenciesOnStart() {
@@ -295,56 +1052,117 @@ func checkDependenciesOnStart() {
 	}
 
 	if !depCheck.Ready {
-		statusLabel.SetText(fmt.Sprintf("依赖错误: %s\n请确保 binaries 目录包含所有必需文件", depCheck.Error))
+		statusLabel.SetText(fmt.Sprintf("依赖错误: %s\n请确保 binaries 目录包含所有必需文件，或通过设置、环境变量、系统 PATH 提供", depCheck.Error))
 		dialog.ShowError(fmt.Errorf("依赖检查失败: %s", depCheck.Error), mainWindow)
+	} else if report := runDoctor(context.Background(), depCheck); len(report.Problems()) > 0 {
+		statusLabel.SetText(fmt.Sprintf("依赖诊断发现问题:\n%s\n可点击“依赖诊断”查看详情", strings.Join(report.Problems(), "\n")))
+	} else if warnings := report.Warnings(); len(warnings) > 0 {
+		statusLabel.SetText(fmt.Sprintf("⚠️ %s\n可以处理，可点击“依赖诊断”查看详情", strings.Join(warnings, "\n")))
 	} else {
 		statusLabel.SetText("依赖检查完成，准备就绪")
 	}
 }
 
-func checkDependencies() (*DependencyCheck, error) {
-	binariesPath, err := getBinariesPath()
+func cleanupOutputsOnStart() {
+	outputDir, err := getOutputDir()
 	if err != nil {
-		return nil, err
+		return
+	}
+	if removed, err := cleanupStaleOutputs(outputDir); err == nil && removed > 0 {
+		fyne.Do(func() {
+			statusLabel.SetText(fmt.Sprintf("%s\n已清理 %d 个上次遗留的临时输出文件", statusLabel.Text, removed))
+		})
 	}
+}
 
-	ffmpegPath := filepath.Join(binariesPath, "ffmpeg")
-	ffprobePath := filepath.Join(binariesPath, "ffprobe")
-	rifePath := filepath.Join(binariesPath, "rife-ncnn-vulkan")
-	modelPath := filepath.Join(binariesPath, "rife-v4.6")
+func scanOrphanWorkDirsOnStart() {
+	orphans, err := findOrphanWorkDirs()
+	if err != nil || len(orphans) == 0 {
+		return
+	}
 
-	// Windows 添加 .exe 扩展名
-	if runtime.GOOS == "windows" {
-		ffmpegPath += ".exe"
-		ffprobePath += ".exe"
-		rifePath += ".exe"
+	var total int64
+	for _, dir := range orphans {
+		total += dir.Size
 	}
+	fyne.Do(func() {
+		statusLabel.SetText(fmt.Sprintf("%s\n发现 %d 个遗留工作目录（共 %s），可点击“清理遗留文件”删除", statusLabel.Text, len(orphans), formatBytes(total)))
+	})
+}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(ffmpegPath); os.IsNotExist(err) {
-		return &DependencyCheck{Ready: false, Error: "FFmpeg 未找到"}, nil
+func checkDependencies() (*DependencyCheck, error) {
+	bundled := &bundledDir{}
+	paths := &BinaryPaths{}
+	sources := BinarySources{}
+	engine := appConfig.engine()
+
+	// 依次查找每个依赖，并记录来源
+	var err error
+	if paths.FFmpeg, sources.FFmpeg, err = resolveDependency(dependencySpec{
+		label: "FFmpeg", configPath: appConfig.FFmpegPath, envVar: "FPS2X_FFMPEG", fileName: "ffmpeg",
+	}, bundled); err != nil {
+		return &DependencyCheck{Ready: false, Error: err.Error(), Paths: paths, Sources: sources, Engine: engine}, nil
 	}
-	if _, err := os.Stat(ffprobePath); os.IsNotExist(err) {
-		return &DependencyCheck{Ready: false, Error: "FFprobe 未找到"}, nil
+	if paths.FFprobe, sources.FFprobe, err = resolveDependency(dependencySpec{
+		label: "FFprobe", configPath: appConfig.FFprobePath, envVar: "FPS2X_FFPROBE", fileName: "ffprobe",
+	}, bundled); err != nil {
+		return &DependencyCheck{Ready: false, Error: err.Error(), Paths: paths, Sources: sources, Engine: engine}, nil
 	}
-	if _, err := os.Stat(rifePath); os.IsNotExist(err) {
-		return &DependencyCheck{Ready: false, Error: "RIFE 主程序未找到"}, nil
+	// 直接选择 ffmpeg 滤镜时仍然查找插帧程序和模型，供诊断和模型管理显示
+	selected, _ := appConfig.ffmpegBackend()
+
+	// 插帧程序或模型不可用时仍然可以处理，回退到 ffmpeg 滤镜插帧
+	fallback := func(reason string) (*DependencyCheck, error) {
+		check := &DependencyCheck{Ready: true, Paths: paths, Sources: sources, Engine: engine, Backend: selected}
+		if selected == nil {
+			check.Backend, check.Fallback = fallbackInterpolator(nil), reason
+		}
+		return check, nil
 	}
-	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
-		return &DependencyCheck{Ready: false, Error: "RIFE 模型文件未找到"}, nil
+	if paths.Interpolator, sources.Interpolator, err = resolveDependency(dependencySpec{
+		label: engine.Label + " 主程序", configPath: appConfig.enginePath(engine), envVar: engine.EnvVar, fileName: engine.Binary,
+	}, bundled); err != nil {
+		return fallback(err.Error())
+	}
+	modelName := appConfig.modelName()
+	paths.Model, sources.Model, err = resolveDependency(dependencySpec{
+		label: engine.Label + " 模型 " + modelName, configPath: appConfig.ModelDir, envVar: "FPS2X_MODEL_DIR", fileName: modelName, isDir: true,
+	}, bundled)
+	// binaries 目录中没有或不完整时，依次在用户模型目录和插帧程序所在目录中查找
+	// 目录存在不代表模型可用，缺少权重文件时插帧程序会在加载阶段才失败
+	if err != nil || (validateModelDir(engine, paths.Model) != nil && (sources.Model == SourceBundled || sources.Model == SourceEmbedded)) {
+		if modelPath, source, ok := resolveModelFallback(engine, paths.Interpolator, modelName); ok {
+			paths.Model, sources.Model, err = modelPath, source, nil
+		}
+	}
+	if err != nil {
+		return fallback(err.Error())
+	}
+	if err := validateModelDir(engine, paths.Model); err != nil {
+		return fallback(fmt.Sprintf("%s 模型 %s 无效: %v", engine.Label, filepath.Base(paths.Model), err))
 	}
 
 	return &DependencyCheck{
-		Ready: true,
-		Paths: &BinaryPaths{
-			FFmpeg:  ffmpegPath,
-			FFprobe: ffprobePath,
-			RIFE:    rifePath,
-			Model:   modelPath,
-		},
+		Ready:   true,
+		Paths:   paths,
+		Sources: sources,
+		Engine:  engine,
+		Backend: selected,
 	}, nil
 }
 
+// resolveBinariesPath 优先使用外部的 binaries 目录，不存在时使用解压到缓存目录的内嵌文件
+func resolveBinariesPath() (string, DependencySource, error) {
+	binariesPath, err := getBinariesPath()
+	if err == nil {
+		if _, err := os.Stat(binariesPath); err == nil {
+			return binariesPath, SourceBundled, nil
+		}
+	}
+	embeddedPath, err := extractEmbeddedBinaries()
+	return embeddedPath, SourceEmbedded, err
+}
+
 func getBinariesPath() (string, error) {
 	// 开发环境：使用项目根目录的 binaries
 	if _, err := os.Stat("binaries"); err == nil {
@@ -368,252 +1186,420 @@ func getBinariesPath() (string, error) {
 	return filepath.Join(filepath.Dir(exePath), "binaries"), nil
 }
 
-func processVideo(inputPath string) {
-	defer func() {
-		fyne.Do(func() {
-			selectBtn.Enable()
-			processBtn.Enable()
-		})
-	}()
-
+// processVideo 处理一个视频，进度和结果通过 ui 报告
+// mode 为 outputModes 中的名称；各阶段在调度器的资源槽位中运行，多个任务可以同时处理
+func processVideo(inputPath, mode string, ui jobReporter) {
 	// 检查依赖
 	depCheck, err := checkDependencies()
 	if err != nil {
-		showError(fmt.Sprintf("依赖检查失败: %v", err))
+		ui.Error(fmt.Sprintf("依赖检查失败: %v", err))
 		return
 	}
 
 	if !depCheck.Ready {
-		showError(depCheck.Error)
+		ui.Error(depCheck.Error)
 		return
 	}
 
 	paths := depCheck.Paths
 
+	// 实际运行依赖，确认版本和编码器、滤镜支持
+	ui.Progress("正在诊断依赖...", 5)
+	report := runDoctor(context.Background(), depCheck)
+	if problems := report.Problems(); len(problems) > 0 {
+		ui.Error(strings.Join(problems, "\n"))
+		return
+	}
+	// 回退到 ffmpeg 滤镜时在任务日志中注明画质较低
+	for _, warning := range report.Warnings() {
+		ui.Log("⚠️ " + warning)
+	}
+	if err := appConfig.EngineTuning.validate(); err != nil {
+		ui.Error(fmt.Sprintf("插帧高级参数无效: %v", err))
+		return
+	}
+	if err := appConfig.QualityModes.validate(); err != nil {
+		ui.Error(fmt.Sprintf("画质模式无效: %v", err))
+		return
+	}
+	if err := appConfig.VFRSettings.validate(); err != nil {
+		ui.Error(fmt.Sprintf("可变帧率设置无效: %v", err))
+		return
+	}
+
 	// 创建工作目录
-	downloadsPath, err := os.UserHomeDir()
+	downloadsPath, err := getOutputDir()
+	if err != nil {
+		ui.Error(fmt.Sprintf("无法获取用户目录: %v", err))
+		return
+	}
+
+	workRoot, err := getWorkRoot()
 	if err != nil {
-		showError(fmt.Sprintf("无法获取用户目录: %v", err))
+		ui.Error(fmt.Sprintf("无法获取工作目录: %v", err))
 		return
 	}
 
-	downloadsPath = filepath.Join(downloadsPath, "Downloads")
 	fileName := filepath.Base(inputPath)
 	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
-	workDir := filepath.Join(downloadsPath, fmt.Sprintf("work_%s_%d", baseName, getCurrentTimestamp()))
 
-	// 创建工作目录
-	if err := os.MkdirAll(filepath.Join(workDir, "in"), 0755); err != nil {
-		showError(fmt.Sprintf("创建工作目录失败: %v", err))
+	// 1. 一次读取帧率、分辨率、时长等信息，无法处理的文件在创建工作目录之前报错
+	ui.Progress("正在获取视频信息...", 10)
+	info, err := probeMedia(context.Background(), paths.FFprobe, inputPath)
+	if err != nil {
+		ui.Error(fmt.Sprintf("读取视频信息失败: %v", err))
 		return
 	}
-	if err := os.MkdirAll(filepath.Join(workDir, "out"), 0755); err != nil {
-		showError(fmt.Sprintf("创建工作目录失败: %v", err))
+	video, _ := info.Video()
+	fpsOrigin := video.FrameRate
+	width, height := video.DisplaySize()
+	duration := info.VideoDuration()
+
+	selectedMode, ok := findOutputMode(mode)
+	if !ok {
+		selectedMode = outputModes[0]
+	}
+
+	// 可变帧率的视频统一为固定帧率，或保留原始时间戳；之后按名义帧率计算目标帧率和用量
+	// 不能保留时间戳时改为统一为固定帧率，帧率同样使用设置中的值
+	var vfr *vfrHandling
+	if analysis, err := detectVFR(context.Background(), paths.FFprobe, inputPath, video); err != nil {
+		ui.Log(fmt.Sprintf("⚠️ 无法检测是否为可变帧率，按固定帧率处理: %v", err))
+	} else if analysis.Variable {
+		ui.Log(fmt.Sprintf("检测到可变帧率：%s", analysis.Reason))
+		vfr = appConfig.VFRSettings.handling(analysis)
+		if vfr.Mode == vfrTimestamps {
+			if reason := timestampsUnsupported(selectedMode, vfr.Rate, width, height, duration); reason != "" {
+				vfr = appConfig.VFRSettings.normalized(analysis)
+				ui.Log(fmt.Sprintf("⚠️ %s，改为统一为固定帧率 %s", reason, vfr.Rate.Label()))
+			}
+		}
+		fpsOrigin = vfr.Rate
+	}
+
+	// 根据模式计算目标帧率，帧率全程以分数表示
+	fpsTarget := selectedMode.targetFrameRate(fpsOrigin)
+	// 按插帧比值估算临时空间：流水线只需保存拆出的帧和少量窗口；预计用量仍超过临时空间上限时改为分段处理
+	format := appConfig.frameFormat()
+	planScratch := func(ratio frameRate, needFFMpegInterpolate bool) (estimate diskEstimate, streaming bool, chunkFrames int) {
+		estimate = estimateDiskUsage(width, height, duration, fpsOrigin.Float(), fpsTarget.Float(), ratio.Float(), needFFMpegInterpolate, format)
+		streaming = appConfig.Pipeline
+		if streaming {
+			estimate.ScratchBytes = estimate.streamingScratch(streamWindowSize(ratio))
+		}
+		if limit := appConfig.scratchLimitBytes(); limit > 0 && estimate.ScratchBytes > limit {
+			streaming = false
+			chunkFrames = chunkFramesForLimit(limit, width, height, ratio, format)
+			estimate.ScratchBytes = estimate.chunkedScratch(limit)
+		}
+		return estimate, streaming, chunkFrames
+	}
+
+	// 支持任意时间步的模型直接按目标帧率插帧（如 24 帧到 60 帧为 5/2 倍），
+	// 其他情况先做整数倍插帧，再由 FFmpeg 补充到目标帧率
+	model := filepath.Base(paths.Model)
+	fallback := frameRate{int64(selectedMode.fallbackMultiplier(fpsOrigin)), 1}
+	ratio := fallback
+	if direct := selectedMode.directRatio(fpsOrigin); !direct.IsZero() && report.interpolator.SupportsRatio(model, direct) {
+		// 分段和流水线窗口的帧数按分母对齐，分母过大或分段过短时改为整数倍插帧，并在日志中说明
+		estimate, _, chunkFrames := planScratch(direct, false)
+		minFrames := minDenominatorsPerUnit * int(direct.Den)
+		switch {
+		case direct.Den > maxRatioDenominator:
+			ui.Log(fmt.Sprintf("⚠️ %s 到 %s 帧的插帧比值为 %s，分母超过 %d，改为 %s 倍插帧后由 FFmpeg 补帧", fpsOrigin.Label(), fpsTarget.Label(), direct, maxRatioDenominator, fallback.Label()))
+		case estimate.Frames < int64(minFrames):
+			ui.Log(fmt.Sprintf("⚠️ 视频只有 %d 帧，按 %s 倍直接插帧至少需要 %d 帧（补齐的重复帧过多），改为 %s 倍插帧后由 FFmpeg 补帧", estimate.Frames, direct, minFrames, fallback.Label()))
+		case chunkFrames > 0 && chunkFrames < minFrames:
+			ui.Log(fmt.Sprintf("⚠️ 临时空间上限下每段只能处理 %d 帧，按 %s 倍直接插帧每段至少需要 %d 帧，改为 %s 倍插帧后由 FFmpeg 补帧", chunkFrames, direct, minFrames, fallback.Label()))
+		default:
+			ratio = direct
+		}
+	}
+	if !report.interpolator.SupportsRatio(model, ratio) {
+		ui.Error(fmt.Sprintf("模型 %s 只支持 2 倍插帧（或 4、8 倍等多次 2 倍），无法做 %s 倍插帧，请更换模型或插帧倍数", model, ratio))
 		return
 	}
-	defer os.RemoveAll(workDir) // 清理临时文件
+	needFFMpegInterpolate := fpsTarget != fpsOrigin.Scale(ratio)
+
+	ui.Progress(fmt.Sprintf("帧率转换: %s -> %s（%s 倍插帧）", fpsOrigin.Label(), fpsTarget.Label(), ratio.Label()), 20)
+	// 29.97 选 60 帧、30 选 59.94 帧时都需要补帧，提示帧率相近、可以直接插帧的固定帧率模式
+	for _, other := range outputModes {
+		if !needFFMpegInterpolate || other.Target.IsZero() || other.Target == fpsTarget || math.Abs(other.Target.Float()/fpsTarget.Float()-1) > 0.01 {
+			continue
+		}
+		if direct := other.directRatio(fpsOrigin); !direct.IsZero() && direct.Den <= maxRatioDenominator && report.interpolator.SupportsRatio(model, direct) {
+			ui.Log(fmt.Sprintf("⚠️ 原始帧率为 %s，输出 %s 帧需要 FFmpeg 补帧；选择“%s”可直接插帧", fpsOrigin.Label(), fpsTarget.Label(), other.Label))
+		}
+	}
 
-	// 1. 获取原始帧率和分辨率
-	updateProgress("正在获取视频信息...", 10)
-	fpsOrigin, err := getFrameRate(inputPath, paths.FFprobe)
+	// 锁定输出文件，防止多个任务写入同一路径
+	// 使用 RIFE 以外的插帧方式时在文件名中注明，便于对比不同引擎的结果
+	outputName := fmt.Sprintf("%s_%sfps", baseName, fpsTarget.Label())
+	if report.Interpolation != defaultEngineName {
+		outputName += "_" + report.Interpolation
+	}
+	output, err := acquireOutput(filepath.Join(downloadsPath, outputName+".mp4"))
 	if err != nil {
-		showError(fmt.Sprintf("获取视频帧率失败: %v", err))
+		ui.Error(err.Error())
+		return
+	}
+	defer output.Release()
+
+	// 检查磁盘空间是否足够
+	if err := report.checkJobSupport(format, needFFMpegInterpolate); err != nil {
+		ui.Error(err.Error())
 		return
 	}
+	estimate, streaming, chunkFrames := planScratch(ratio, needFFMpegInterpolate)
+
+	// 保留时间戳时读取每一帧的原始时间，此时一定是完整拆帧后一次封装
+	if vfr != nil && vfr.Mode == vfrTimestamps {
+		times, err := probeFrameTimes(context.Background(), paths.FFprobe, inputPath, 0)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		vfr.Times = times
+	}
+	if vfr != nil {
+		ui.Log("可变帧率处理方式: " + vfr.String())
+	}
 
-	width, height, err := getVideoResolution(inputPath, paths.FFprobe)
+	spaceWarning, err := checkDiskSpace(estimate, workRoot, downloadsPath)
 	if err != nil {
-		showError(fmt.Sprintf("获取视频分辨率失败: %v", err))
+		ui.Error(err.Error())
 		return
 	}
+	if spaceWarning != "" {
+		ui.Log(spaceWarning)
+	}
 
-	// 计算像素数量，用于判断是否为高分辨率
-	totalPixels := width * height
-	isHighRes := totalPixels > 1920*1080 // 超过1080p算高分辨率
-	is4K := totalPixels > 3840*2160*0.9 // 接近或超过4K
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
 
-	// 根据模式计算目标帧率
-	var fpsTarget float64
-	var needFFMpegInterpolate bool // 是否需要FFmpeg补充插帧
+	// 多个任务同时处理时，临时文件总量不超过磁盘预算；任务中止时停止等待
+	scheduler := currentScheduler()
+	releaseDisk, err := scheduler.reserveDisk(ctx, estimate.ScratchBytes, func() {
+		ui.Progress(fmt.Sprintf("等待磁盘预算（需要 %s，其他任务结束后开始）...", formatBytes(estimate.ScratchBytes)), 25)
+	})
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	defer releaseDisk()
 
-	if outputMode == "60fps" {
-		fpsTarget = 60.0
-		// 检查是否为整数倍关系
-		if fpsTarget/fpsOrigin != 2.0 && fpsTarget/fpsOrigin != 3.0 && fpsTarget/fpsOrigin != 4.0 {
-			needFFMpegInterpolate = true
-		}
-	} else {
-		fpsTarget = fpsOrigin * 2
+	workDir, err := createWorkDir(workRoot, baseName)
+	if err != nil {
+		ui.Error(fmt.Sprintf("创建工作目录失败: %v", err))
+		return
 	}
+	defer os.RemoveAll(workDir) // 清理临时文件
 
-	updateProgress(fmt.Sprintf("帧率转换: %.0f -> %.0f", fpsOrigin, fpsTarget), 20)
+	// 处理过程中持续监控剩余空间，不足时中止正在运行的命令
+	go monitorDiskSpace(ctx, cancel, workRoot, downloadsPath)
 
 	// 2. 提取音频
-	updateProgress("正在提取音频...", 30)
+	ui.Progress("正在提取音频...", 30)
 	audioPath := filepath.Join(workDir, "audio.m4a")
-	if err := runCommand(paths.FFmpeg, []string{
-		"-y", "-i", inputPath, "-vn", "-c:a", "copy", audioPath,
+	if err := scheduler.run(ctx, slotEncode, func() error {
+		return runCommand(ctx, paths.FFmpeg, []string{"-y", "-i", inputPath, "-vn", "-c:a", "copy", audioPath})
 	}); err != nil {
-		showError(fmt.Sprintf("提取音频失败: %v", err))
+		ui.Error(fmt.Sprintf("提取音频失败: %v", err))
+		return
+	}
+
+	job := &videoJob{
+		paths:                 paths,
+		interpolator:          report.interpolator,
+		inputPath:             inputPath,
+		workDir:               workDir,
+		audioPath:             audioPath,
+		output:                output,
+		ui:                    ui,
+		scheduler:             scheduler,
+		format:                format,
+		vfr:                   vfr,
+		codec:                 report.VideoCodec(),
+		tuning:                appConfig.jobTuning(report.Interpolation, width, height),
+		width:                 width,
+		height:                height,
+		frames:                estimate.Frames,
+		fpsOrigin:             fpsOrigin,
+		startOffset:           max(video.StartTime-info.StartTime, 0),
+		fpsTarget:             fpsTarget,
+		ratio:                 ratio,
+		needFFMpegInterpolate: needFFMpegInterpolate,
+	}
+	if appConfig.BackgroundPriority {
+		ui.Log("后台优先级: 已降低子进程的 CPU 和 I/O 优先级")
+	}
+	if appConfig.FFmpegThreads > 0 {
+		ui.Log(fmt.Sprintf("ffmpeg 线程上限: %d", appConfig.FFmpegThreads))
+	}
+	if engine, ok := job.interpolator.(*interpolationEngine); ok {
+		job.modes = appConfig.QualityModes.resolve(engine, width, height)
+		ui.Log(fmt.Sprintf("%s 参数: %s", engine.Label, job.tuning))
+		ui.Log(fmt.Sprintf("画质模式: %s（插帧耗时约为默认的 %.0f 倍）", job.modes, job.modes.Cost()))
+		if len(job.modes.Ignored) > 0 {
+			ui.Log(fmt.Sprintf("⚠️ %s 不支持 %s，已忽略", engine.Label, strings.Join(job.modes.Ignored, "、")))
+		}
+		if eta, ok := appConfig.estimateInterpolationTime(engine.Name, width, height, ratio.scaleCount(estimate.Frames), job.modes); ok {
+			ui.Log(fmt.Sprintf("预计插帧耗时约 %s", formatETA(eta)))
+		}
+	}
+
+	if streaming || chunkFrames > 0 {
+		if streaming {
+			err = processStreaming(ctx, job)
+		} else {
+			err = processChunked(ctx, job, chunkFrames)
+		}
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		ui.Completed(output.Path)
 		return
 	}
 
 	// 3. 拆帧
-	updateStep(stepExtractLabel, StepRunning, "提取视频帧")
-	updateStepProgress(stepExtractProgress, 0.1) // 开始
-	updateProgress("正在拆帧...", 40)
-	inputFrames := filepath.Join(workDir, "in", "%08d.jpg")
-	if err := runCommand(paths.FFmpeg, []string{
-		"-y", "-i", inputPath, "-q:v", "2", inputFrames,
-	}); err != nil {
-		updateStep(stepExtractLabel, StepError, "提取视频帧")
-		showError(fmt.Sprintf("拆帧失败: %v", err))
-		return
-	}
-	updateStepProgress(stepExtractProgress, 1.0) // 完成
-	updateStep(stepExtractLabel, StepCompleted, "提取视频帧")
-
-	// 4. RIFE 插帧
-	updateStep(stepInterpLabel, StepRunning, "AI 插帧")
-	updateStepProgress(stepInterpProgress, 0.1) // 开始
-	updateProgress("AI 插帧中（这可能需要几分钟）...", 60)
-
-	// 自动计算最佳线程数（保留足够核心给系统）
-	numCPU := runtime.NumCPU()
-
-	// 根据分辨率调整线程策略
-	var loadThreads, procThreads, saveThreads int
-
-	if is4K {
-		// 4K 视频：保守策略，避免显存溢出
-		// 只用少量线程，避免显存不足导致 swap
-		loadThreads = 2
-		procThreads = 4 // 4K用很少的处理线程
-		saveThreads = 2
-		updateProgress("检测到4K视频，使用保守线程设置以避免显存溢出", 50)
-	} else if isHighRes {
-		// 2K/1440p等高分辨率：中等策略
-		var reservedCPU int = 2
-		optimalThreads := numCPU - reservedCPU
-		if optimalThreads > 12 {
-			optimalThreads = 12
-		}
-		loadThreads = int(optimalThreads)
-		procThreads = int(optimalThreads) * 2 // 中等倍数
-		saveThreads = int(optimalThreads)
-	} else {
-		// 1080p及以下：激进策略，最大化性能
-		var reservedCPU int = 1
-		optimalThreads := numCPU - reservedCPU
-		if optimalThreads > 16 {
-			optimalThreads = 16
-		}
-		loadThreads = int(optimalThreads)
-		procThreads = int(optimalThreads) * 4 // 最大倍数
-		saveThreads = int(optimalThreads)
-	}
-
-	if err := runCommand(paths.RIFE, []string{
-		"-i", filepath.Join(workDir, "in"),
-		"-o", filepath.Join(workDir, "out"),
-		"-j", fmt.Sprintf("%d:%d:%d", loadThreads, procThreads, saveThreads),
-		"-m", paths.Model,
-	}); err != nil {
-		updateStep(stepInterpLabel, StepError, "AI 插帧")
-		showError(fmt.Sprintf("AI 插帧失败: %v", err))
+	ui.Step(stepExtract, StepRunning, "提取视频帧")
+	ui.StepProgress(stepExtract, 0.1) // 开始
+	ui.Progress("正在拆帧...", 40)
+
+	// libx264 编码时按关键帧切段，用多个 ffmpeg 进程并行拆帧和编码
+	// 切段依赖固定帧率推算帧号，可变帧率的视频不切段
+	parallelSegments := 1
+	if appConfig.ParallelSegments && vfr == nil {
+		parallelSegments = parallelSegmentCount(job.codec, width, height)
+	}
+
+	err = job.runStage(ctx, slotEncode, func() error {
+		if parallelSegments > 1 {
+			return extractFramesParallel(ctx, job, duration, parallelSegments)
+		}
+		args := append([]string{"-y", "-i", inputPath}, job.extractArgs()...)
+		return runCommand(ctx, paths.FFmpeg, append(args, format.Pattern(filepath.Join(workDir, "in"))))
+	})
+	if err != nil {
+		ui.Step(stepExtract, StepError, "提取视频帧")
+		ui.Error(fmt.Sprintf("拆帧失败: %v", err))
 		return
 	}
-	updateStepProgress(stepInterpProgress, 0.8) // RIFE 完成，可能需要补充
+	ui.StepProgress(stepExtract, 1.0) // 完成
+	ui.Step(stepExtract, StepCompleted, "提取视频帧")
+
+	// 4. 插帧
+	ui.Step(stepInterp, StepRunning, job.stepName())
+	ui.StepProgress(stepInterp, 0.1) // 开始
+	ui.Progress(job.stepName()+"中（这可能需要几分钟）...", 60)
+
+	if is4KResolution(width, height) && job.tuning.Auto && job.tuning.ProcThreads > 0 && !job.interpolator.LowQuality() {
+		ui.Progress("检测到4K视频，使用保守线程设置以避免显存溢出", 50)
+	}
+
+	if err := job.interpolate(ctx, filepath.Join(workDir, "in"), filepath.Join(workDir, "out")); err != nil {
+		ui.Step(stepInterp, StepError, job.stepName())
+		ui.Error(fmt.Sprintf("%s失败: %v", job.stepName(), err))
+		return
+	}
+	ui.StepProgress(stepInterp, 0.8) // 插帧完成，可能需要补充
 
 	// 如果需要FFmpeg补充插帧（非整数倍情况）
 	var finalFramePath string
 	if needFFMpegInterpolate {
-		updateStepProgress(stepInterpProgress, 0.9) // 开始补充
-		updateProgress("正在补充帧率到60fps...", 70)
+		ui.StepProgress(stepInterp, 0.9) // 开始补充
+		ui.Progress(fmt.Sprintf("正在补充帧率到 %s fps...", fpsTarget.Label()), 70)
 
 		// 创建新的输出目录
-		out60Dir := filepath.Join(workDir, "out60")
-		if err := os.MkdirAll(out60Dir, 0755); err != nil {
-			updateStep(stepInterpLabel, StepError, "AI 插帧")
-			showError(fmt.Sprintf("创建输出目录失败: %v", err))
+		fillDir := filepath.Join(workDir, "out_fill")
+		if err := os.MkdirAll(fillDir, 0755); err != nil {
+			ui.Step(stepInterp, StepError, job.stepName())
+			ui.Error(fmt.Sprintf("创建输出目录失败: %v", err))
 			return
 		}
 
 		// 使用FFmpeg的minterpolate滤镜补充帧率
-		// 先将RIFE输出的PNG序列转换为中间视频
+		// 先将RIFE输出的帧序列转换为中间视频
 		tempVideo := filepath.Join(workDir, "temp_rife.mp4")
-		rifeFrameRate := fpsOrigin * 2 // RIFE输出是2倍
+		rifeFrameRate := fpsOrigin.Scale(ratio)
 
-		if err := runCommand(paths.FFmpeg, []string{
+		if err := job.runFFmpeg(ctx, []string{
 			"-y",
-			"-framerate", fmt.Sprintf("%.0f", rifeFrameRate),
-			"-i", filepath.Join(workDir, "out", "%08d.png"),
+			"-framerate", rifeFrameRate.String(),
+			"-i", format.Pattern(filepath.Join(workDir, "out")),
 			"-c:v", "libx264",
 			"-preset", "ultrafast", // 快速编码
 			"-crf", "18",
 			"-pix_fmt", "yuv420p",
 			tempVideo,
 		}); err != nil {
-			updateStep(stepInterpLabel, StepError, "AI 插帧")
-			showError(fmt.Sprintf("生成中间视频失败: %v", err))
+			ui.Step(stepInterp, StepError, job.stepName())
+			ui.Error(fmt.Sprintf("生成中间视频失败: %v", err))
 			return
 		}
 
-		// 使用minterpolate补充到60fps
-		if err := runCommand(paths.FFmpeg, []string{
+		// 使用minterpolate补充到目标帧率
+		args := []string{
 			"-y",
 			"-i", tempVideo,
-			"-filter:v", fmt.Sprintf("minterpolate=fps=60:mi_mode=mci:mc_mode=aobmc:me_mode=bidir_ref:vsbmc=1"),
-			"-c:v", "libx264",
-			"-preset", "ultrafast",
-			"-crf", "18",
-			"-pix_fmt", "yuv420p",
-			filepath.Join(out60Dir, "%08d.png"),
-		}); err != nil {
-			updateStep(stepInterpLabel, StepError, "AI 插帧")
-			showError(fmt.Sprintf("补充帧率失败: %v", err))
+			"-filter:v", fmt.Sprintf("minterpolate=fps=%s:mi_mode=mci:mc_mode=aobmc:me_mode=bidir_ref:vsbmc=1", fpsTarget),
+		}
+		args = append(args, format.EncodeArgs()...)
+		if err := job.runFFmpeg(ctx, append(args, format.Pattern(fillDir))); err != nil {
+			ui.Step(stepInterp, StepError, job.stepName())
+			ui.Error(fmt.Sprintf("补充帧率失败: %v", err))
 			return
 		}
 
-		finalFramePath = out60Dir
-		updateStepProgress(stepInterpProgress, 1.0) // 完成
-		updateStep(stepInterpLabel, StepCompleted, "AI 插帧 + 补充")
+		finalFramePath = fillDir
+		ui.StepProgress(stepInterp, 1.0) // 完成
+		ui.Step(stepInterp, StepCompleted, job.stepName()+" + 补充")
 	} else {
-		updateStepProgress(stepInterpProgress, 1.0) // 完成
-		updateStep(stepInterpLabel, StepCompleted, "AI 插帧")
+		ui.StepProgress(stepInterp, 1.0) // 完成
+		ui.Step(stepInterp, StepCompleted, job.stepName())
 		finalFramePath = filepath.Join(workDir, "out")
 	}
 
 	// 5. 合并视频
-	updateStep(stepMergeLabel, StepRunning, "合并视频")
-	updateStepProgress(stepMergeProgress, 0.1) // 开始
-	updateProgress("正在封装最终视频...", 80)
-	outputPath := filepath.Join(downloadsPath, fmt.Sprintf("%s_%.0ffps.mp4", baseName, fpsTarget))
-
-	// 根据平台选择编码器
-	codec := "libx264"
-	if runtime.GOOS == "darwin" {
-		codec = "h264_videotoolbox"
-	}
-
-	if err := runCommand(paths.FFmpeg, []string{
-		"-y", "-framerate", fmt.Sprintf("%.0f", fpsTarget),
-		"-i", filepath.Join(finalFramePath, "%08d.png"),
-		"-i", audioPath,
-		"-c:v", codec,
-		"-b:v", "15M",
-		"-pix_fmt", "yuv420p",
-		"-c:a", "copy",
-		"-shortest", outputPath,
-	}); err != nil {
-		updateStep(stepMergeLabel, StepError, "合并视频")
-		showError(fmt.Sprintf("封装视频失败: %v", err))
+	ui.Step(stepMerge, StepRunning, "合并视频")
+	ui.StepProgress(stepMerge, 0.1) // 开始
+	ui.Progress("正在封装最终视频...", 80)
+	err = job.runStage(ctx, slotEncode, func() error {
+		if vfr != nil && vfr.Mode == vfrTimestamps {
+			return encodeWithTimestamps(ctx, job, finalFramePath)
+		}
+		if parallelSegments > 1 {
+			return encodeFramesParallel(ctx, job, finalFramePath, parallelSegments)
+		}
+		return runCommand(ctx, paths.FFmpeg, []string{
+			"-y", "-framerate", fpsTarget.String(),
+			"-i", format.Pattern(finalFramePath),
+			"-i", audioPath,
+			"-c:v", job.codec,
+			"-b:v", "15M",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "copy",
+			"-shortest", output.TempPath,
+		})
+	})
+	if err != nil {
+		ui.Step(stepMerge, StepError, "合并视频")
+		ui.Error(fmt.Sprintf("封装视频失败: %v", err))
 		return
 	}
-	updateStepProgress(stepMergeProgress, 1.0) // 完成
-	updateStep(stepMergeLabel, StepCompleted, "合并视频")
+	if err := output.Commit(); err != nil {
+		ui.Step(stepMerge, StepError, "合并视频")
+		ui.Error(err.Error())
+		return
+	}
+	ui.StepProgress(stepMerge, 1.0) // 完成
+	ui.Step(stepMerge, StepCompleted, "合并视频")
+
+	ui.Completed(output.Path)
+}
 
-	// 完成
+func showCompleted(outputPath string) {
 	updateProgress("处理完成！", 100)
 	fyne.Do(func() {
 		resultLabel.SetText(fmt.Sprintf("视频已保存至:\n%s", outputPath))
@@ -622,74 +1608,49 @@ func processVideo(inputPath string) {
 	})
 }
 
-func getFrameRate(inputPath, ffprobePath string) (float64, error) {
-	cmd := exec.Command(ffprobePath,
-		"-v", "error",
-		"-select_streams", "v:0",
-		"-show_entries", "stream=r_frame_rate",
-		"-of", "default=noprint_wrappers=1:nokey=1",
-		inputPath,
-	)
+func runCommand(ctx context.Context, command string, args []string) error {
+	// 只记录命令，不捕获输出以减少内存占用
+	cmd := newCommand(ctx, command, args)
 
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, fmt.Errorf("执行 ffprobe 失败: %w", err)
+	// 直接运行，不捕获输出（避免大量输出占用内存）
+	if err := startCommand(cmd); err != nil {
+		return commandError(ctx, err)
 	}
-
-	fpsStr := strings.TrimSpace(string(output))
-	parts := strings.Split(fpsStr, "/")
-	if len(parts) == 2 {
-		numerator := parseFloat(parts[0])
-		denominator := parseFloat(parts[1])
-		if denominator != 0 {
-			return numerator / denominator, nil
-		}
+	if err := cmd.Wait(); err != nil {
+		return commandError(ctx, err)
 	}
 
-	return parseFloat(fpsStr), nil
+	return nil
 }
 
-func getVideoResolution(inputPath, ffprobePath string) (int, int, error) {
-	cmd := exec.Command(ffprobePath,
-		"-v", "error",
-		"-select_streams", "v:0",
-		"-show_entries", "stream=width,height",
-		"-of", "default=noprint_wrappers=1:nokey=1",
-		inputPath,
-	)
-
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, 0, fmt.Errorf("执行 ffprobe 失败: %w", err)
-	}
-
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(lines) >= 2 {
-		width := parseFloat(lines[0])
-		height := parseFloat(lines[1])
-		return int(width), int(height), nil
+// commandError 包装命令执行失败的错误，被中止时返回中止原因（例如磁盘空间不足）
+func commandError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
 	}
-
-	return 0, 0, fmt.Errorf("无法解析视频分辨率")
+	return fmt.Errorf("命令执行失败: %w", err)
 }
 
-func runCommand(command string, args []string) error {
-	// 只记录命令，不捕获输出以减少内存占用
-	cmd := exec.Command(command, args...)
+func updateProgress(text string, progress float64) {
+	fyne.Do(func() {
+		progressLabel.SetText(text)
+		progressBar.SetValue(progress / 100)
+	})
+}
 
-	// 直接运行，不捕获输出（避免大量输出占用内存）
-	err := cmd.Run()
-	if err != nil {
-		return fmt.Errorf("命令执行失败: %w", err)
+// formatETA 将预计耗时格式化为分钟或秒
+func formatETA(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.0f 秒", d.Seconds())
 	}
-
-	return nil
+	return fmt.Sprintf("%.0f 分钟", d.Minutes())
 }
 
-func updateProgress(text string, progress float64) {
+// logJob 在状态栏追加一行任务日志，同时输出到标准错误，便于从终端排查
+func logJob(text string) {
+	fmt.Fprintln(os.Stderr, text)
 	fyne.Do(func() {
-		progressLabel.SetText(text)
-		progressBar.SetValue(progress / 100)
+		statusLabel.SetText(statusLabel.Text + "\n" + text)
 	})
 }
 
@@ -742,9 +1703,3 @@ func showError(message string) {
 func getCurrentTimestamp() int64 {
 	return time.Now().Unix()
 }
-
-func parseFloat(s string) float64 {
-	var f float64
-	fmt.Sscanf(s, "%f", &f)
-	return f
-}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MediaInfo 是一次 ffprobe 读取到的文件信息
+type MediaInfo struct {
+	Format    string       `json:"format"`     // 容器格式，如 mov,mp4,m4a,3gp,3g2,mj2
+	Duration  float64      `json:"duration"`   // 秒
+	StartTime float64      `json:"start_time"` // 秒
+	BitRate   int64        `json:"bit_rate"`   // 总码率（bit/s），未知时为 0
+	Size      int64        `json:"size"`       // 字节
+	Streams   []StreamInfo `json:"streams"`
+}
+
+// StreamInfo 是文件中的一路流，未知的数值字段为 0
+type StreamInfo struct {
+	Index     int     `json:"index"`
+	Type      string  `json:"type"` // video/audio/subtitle/data
+	Codec     string  `json:"codec"`
+	Profile   string  `json:"profile,omitempty"`
+	Duration  float64 `json:"duration,omitempty"`
+	StartTime float64 `json:"start_time,omitempty"`
+	BitRate   int64   `json:"bit_rate,omitempty"`
+	Language  string  `json:"language,omitempty"`
+
+	// 视频流
+	Width          int       `json:"width,omitempty"`
+	Height         int       `json:"height,omitempty"`
+	FrameRate      frameRate `json:"frame_rate,omitzero"`     // r_frame_rate
+	AvgFrameRate   frameRate `json:"avg_frame_rate,omitzero"` // avg_frame_rate
+	Frames         int64     `json:"frames,omitempty"`        // nb_frames，部分容器（如 mkv）不记录
+	Rotation       int       `json:"rotation,omitempty"`      // 顺时针旋转角度，0/90/180/270
+	PixelFormat    string    `json:"pix_fmt,omitempty"`
+	ColorSpace     string    `json:"color_space,omitempty"`
+	ColorTransfer  string    `json:"color_transfer,omitempty"`
+	ColorPrimaries string    `json:"color_primaries,omitempty"`
+	ColorRange     string    `json:"color_range,omitempty"`
+	AttachedPic    bool      `json:"attached_pic,omitempty"` // 封面图片，不是真正的视频
+
+	// 音频流
+	SampleRate int `json:"sample_rate,omitempty"`
+	Channels   int `json:"channels,omitempty"`
+}
+
+// ffprobeOutput 对应 ffprobe -of json 的输出，数值大多以字符串表示
+type ffprobeOutput struct {
+	Streams []struct {
+		Index          int               `json:"index"`
+		CodecType      string            `json:"codec_type"`
+		CodecName      string            `json:"codec_name"`
+		Profile        string            `json:"profile"`
+		Width          int               `json:"width"`
+		Height         int               `json:"height"`
+		RFrameRate     string            `json:"r_frame_rate"`
+		AvgFrameRate   string            `json:"avg_frame_rate"`
+		NbFrames       string            `json:"nb_frames"`
+		Duration       string            `json:"duration"`
+		StartTime      string            `json:"start_time"`
+		BitRate        string            `json:"bit_rate"`
+		PixFmt         string            `json:"pix_fmt"`
+		ColorSpace     string            `json:"color_space"`
+		ColorTransfer  string            `json:"color_transfer"`
+		ColorPrimaries string            `json:"color_primaries"`
+		ColorRange     string            `json:"color_range"`
+		SampleRate     string            `json:"sample_rate"`
+		Channels       int               `json:"channels"`
+		Tags           map[string]string `json:"tags"`
+		Disposition    map[string]int    `json:"disposition"`
+		SideDataList   []struct {
+			SideDataType string  `json:"side_data_type"`
+			Rotation     float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		StartTime  string `json:"start_time"`
+		BitRate    string `json:"bit_rate"`
+		Size       string `json:"size"`
+	} `json:"format"`
+}
+
+// probeMedia 用一次 ffprobe 读取文件的所有流和容器信息，并检查是否可以处理
+// 纯音频、时长为 0 或无法读取的文件直接返回错误，此时还没有创建任何工作目录
+func probeMedia(ctx context.Context, ffprobePath, inputPath string) (*MediaInfo, error) {
+	cmd := exec.CommandContext(ctx, ffprobePath,
+		"-v", "error",
+		"-show_streams",
+		"-show_format",
+		"-of", "json",
+		inputPath,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("无法读取文件: %s", lastLine(message))
+		}
+		return nil, fmt.Errorf("执行 ffprobe 失败: %w", err)
+	}
+
+	info, err := parseMediaInfo(output)
+	if err != nil {
+		return nil, err
+	}
+	if err := info.validate(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// parseMediaInfo 解析 ffprobe 的 JSON 输出，缺失或为 N/A 的字段记为 0
+func parseMediaInfo(data []byte) (*MediaInfo, error) {
+	var raw ffprobeOutput
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析 ffprobe 输出失败: %w", err)
+	}
+
+	info := &MediaInfo{
+		Format:    raw.Format.FormatName,
+		Duration:  optionalFloat(raw.Format.Duration),
+		StartTime: optionalFloat(raw.Format.StartTime),
+		BitRate:   optionalInt(raw.Format.BitRate),
+		Size:      optionalInt(raw.Format.Size),
+	}
+	for _, s := range raw.Streams {
+		stream := StreamInfo{
+			Index:          s.Index,
+			Type:           s.CodecType,
+			Codec:          s.CodecName,
+			Profile:        s.Profile,
+			Duration:       optionalFloat(s.Duration),
+			StartTime:      optionalFloat(s.StartTime),
+			BitRate:        optionalInt(s.BitRate),
+			Language:       s.Tags["language"],
+			Width:          s.Width,
+			Height:         s.Height,
+			Frames:         optionalInt(s.NbFrames),
+			PixelFormat:    s.PixFmt,
+			ColorSpace:     s.ColorSpace,
+			ColorTransfer:  s.ColorTransfer,
+			ColorPrimaries: s.ColorPrimaries,
+			ColorRange:     s.ColorRange,
+			AttachedPic:    s.Disposition["attached_pic"] == 1,
+			SampleRate:     int(optionalInt(s.SampleRate)),
+			Channels:       s.Channels,
+		}
+		stream.FrameRate, _ = parseFrameRate(s.RFrameRate)
+		stream.AvgFrameRate, _ = parseFrameRate(s.AvgFrameRate)
+
+		// 旧版 ffprobe 在 rotate 标签中给出顺时针角度，新版在显示矩阵中给出逆时针角度
+		rotation := 0.0
+		if tag, ok := s.Tags["rotate"]; ok {
+			rotation = optionalFloat(tag)
+		}
+		for _, side := range s.SideDataList {
+			if side.SideDataType == "Display Matrix" {
+				rotation = -side.Rotation
+			}
+		}
+		stream.Rotation = (int(math.Round(rotation))%360 + 360) % 360
+
+		info.Streams = append(info.Streams, stream)
+	}
+	return info, nil
+}
+
+// validate 检查文件是否包含可处理的视频流
+func (m *MediaInfo) validate() error {
+	video, ok := m.Video()
+	if !ok {
+		if _, hasAudio := m.Audio(); hasAudio {
+			return errors.New("文件只包含音频，没有视频流")
+		}
+		return errors.New("文件中没有视频流")
+	}
+	if video.Width <= 0 || video.Height <= 0 {
+		return fmt.Errorf("无法读取视频分辨率（%dx%d）", video.Width, video.Height)
+	}
+	if video.FrameRate.IsZero() {
+		return errors.New("无法读取视频帧率")
+	}
+	if m.VideoDuration() <= 0 {
+		return errors.New("视频时长为 0")
+	}
+	return nil
+}
+
+// Video 返回第一路视频流，不包括封面图片
+func (m *MediaInfo) Video() (*StreamInfo, bool) {
+	for i := range m.Streams {
+		if m.Streams[i].Type == "video" && !m.Streams[i].AttachedPic {
+			return &m.Streams[i], true
+		}
+	}
+	return nil, false
+}
+
+// Audio 返回第一路音频流
+func (m *MediaInfo) Audio() (*StreamInfo, bool) {
+	for i := range m.Streams {
+		if m.Streams[i].Type == "audio" {
+			return &m.Streams[i], true
+		}
+	}
+	return nil, false
+}
+
+// VideoDuration 返回视频时长，容器未记录时使用视频流的时长
+func (m *MediaInfo) VideoDuration() float64 {
+	if m.Duration > 0 {
+		return m.Duration
+	}
+	if video, ok := m.Video(); ok {
+		return video.Duration
+	}
+	return 0
+}
+
+// DisplaySize 返回按旋转角度校正后的画面尺寸；ffmpeg 拆帧时会自动旋转，拆出的帧即为该尺寸
+func (s *StreamInfo) DisplaySize() (width, height int) {
+	if s.Rotation == 90 || s.Rotation == 270 {
+		return s.Height, s.Width
+	}
+	return s.Width, s.Height
+}
+
+func (m *MediaInfo) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "格式: %s  时长: %.2f 秒", m.Format, m.VideoDuration())
+	if m.BitRate > 0 {
+		fmt.Fprintf(&b, "  码率: %.1f Mbps", float64(m.BitRate)/1e6)
+	}
+	if m.StartTime != 0 {
+		fmt.Fprintf(&b, "  起始时间: %.3f 秒", m.StartTime)
+	}
+	b.WriteString("\n")
+	for _, s := range m.Streams {
+		fmt.Fprintf(&b, "#%d %s %s", s.Index, s.Type, s.Codec)
+		if s.Profile != "" {
+			fmt.Fprintf(&b, "（%s）", s.Profile)
+		}
+		switch s.Type {
+		case "video":
+			fmt.Fprintf(&b, " %dx%d %s fps（%s）", s.Width, s.Height, s.FrameRate.Label(), s.FrameRate)
+			if s.Frames > 0 {
+				fmt.Fprintf(&b, " %d 帧", s.Frames)
+			}
+			if s.Rotation != 0 {
+				fmt.Fprintf(&b, " 旋转 %d°", s.Rotation)
+			}
+			for _, value := range []string{s.PixelFormat, s.ColorSpace, s.ColorTransfer, s.ColorPrimaries, s.ColorRange} {
+				if value != "" && value != "unknown" {
+					b.WriteString(" " + value)
+				}
+			}
+			if s.AttachedPic {
+				b.WriteString(" 封面")
+			}
+		case "audio":
+			fmt.Fprintf(&b, " %d Hz %d 声道", s.SampleRate, s.Channels)
+		}
+		if s.Language != "" {
+			b.WriteString(" " + s.Language)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// optionalFloat 解析 ffprobe 中以字符串表示的数值，缺失或为 N/A 时返回 0
+func optionalFloat(text string) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0
+	}
+	return value
+}
+
+func optionalInt(text string) int64 {
+	value, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// lastLine 返回多行输出的最后一行，ffprobe 的错误原因通常在最后
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ncnn 模型结构文件第一行的固定标识
+const ncnnParamMagic = "7767517"
+
+// EngineModel 是一个已发现的插帧模型目录及其能力
+type EngineModel struct {
+	Name   string           `json:"name"`
+	Engine string           `json:"engine"`
+	Path   string           `json:"path"`
+	Source DependencySource `json:"source"`
+	Error  string           `json:"error,omitempty"` // 为空表示模型文件完整
+
+	Version    string    `json:"version"` // 目录名中引擎前缀之后的部分，如 rife-v4.6 的 v4.6
+	Size       int64     `json:"size"`
+	ImportedAt time.Time `json:"imported_at,omitzero"` // 仅用户导入的模型
+
+	// 支持任意时间步的模型可一次生成任意数量的中间帧
+	ArbitraryTimestep bool `json:"arbitrary_timestep"`
+}
+
+func (m EngineModel) Valid() bool {
+	return m.Error == ""
+}
+
+// modelSearchRoot 是查找模型目录的位置
+type modelSearchRoot struct {
+	dir    string
+	source DependencySource
+}
+
+// modelSearchRoots 返回 binaries 目录（或内嵌文件）之后的模型查找位置：用户模型目录和插帧程序所在目录
+func modelSearchRoots(enginePath string) []modelSearchRoot {
+	var roots []modelSearchRoot
+	if dir, err := getUserModelsDir(); err == nil {
+		roots = append(roots, modelSearchRoot{dir, SourceUser})
+	}
+	if enginePath != "" {
+		roots = append(roots, modelSearchRoot{filepath.Dir(enginePath), SourceNearEngine})
+	}
+	return roots
+}
+
+// discoverModels 在 binaries 目录（或内嵌文件）、用户模型目录和插帧程序所在目录中查找引擎的所有模型
+// 同名模型以先找到的完整模型为准，顺序与依赖查找一致
+func discoverModels(engine *interpolationEngine, enginePath string, bundled *bundledDir) []EngineModel {
+	var roots []modelSearchRoot
+	if dir, source, err := bundled.get(); err == nil {
+		roots = append(roots, modelSearchRoot{dir, source})
+	}
+	roots = append(roots, modelSearchRoots(enginePath)...)
+
+	seen := map[string]int{}
+	var models []EngineModel
+	for _, root := range roots {
+		entries, err := os.ReadDir(root.dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() || !engine.ownsModel(name) {
+				continue
+			}
+			model := inspectModel(engine, filepath.Join(root.dir, name), root.source)
+			if i, ok := seen[name]; ok {
+				if !models[i].Valid() && model.Valid() {
+					models[i] = model
+				}
+				continue
+			}
+			seen[name] = len(models)
+			models = append(models, model)
+		}
+	}
+
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models
+}
+
+// inspectModel 校验模型目录并记录其能力、大小和版本
+func inspectModel(engine *interpolationEngine, path string, source DependencySource) EngineModel {
+	name := filepath.Base(path)
+	model := EngineModel{
+		Name:              name,
+		Engine:            engine.Name,
+		Path:              path,
+		Source:            source,
+		Version:           strings.TrimLeft(strings.TrimPrefix(name, engine.ModelPrefix), "-_"),
+		Size:              dirSize(path),
+		ArbitraryTimestep: engine.supportsTimestep(name),
+	}
+	if meta, ok := readModelMeta(path); ok {
+		model.ImportedAt = meta.ImportedAt
+	}
+	if err := validateModelDir(engine, path); err != nil {
+		model.Error = err.Error()
+	}
+	return model
+}
+
+// resolveModelFallback 在用户模型目录和插帧程序所在目录中查找指定名称的模型
+// 优先返回完整的模型；都不完整时返回第一个找到的目录，以便报告具体问题
+func resolveModelFallback(engine *interpolationEngine, enginePath, modelName string) (string, DependencySource, bool) {
+	var firstPath string
+	var firstSource DependencySource
+	for _, root := range modelSearchRoots(enginePath) {
+		path := filepath.Join(root.dir, modelName)
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			continue
+		}
+		if validateModelDir(engine, path) == nil {
+			return path, root.source, true
+		}
+		if firstPath == "" {
+			firstPath, firstSource = path, root.source
+		}
+	}
+	return firstPath, firstSource, firstPath != ""
+}
+
+// validateModelDir 检查模型目录中的 .param 和 .bin 是否成对存在
+// 只有 param 没有 bin（或 bin 为空）时插帧程序会在加载模型时才失败，需要提前发现
+func validateModelDir(engine *interpolationEngine, path string) error {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("模型目录不存在: %s", path)
+	}
+
+	var networks []string
+	if engine.NetworkFile != "" {
+		networks = []string{engine.NetworkFile}
+	} else {
+		params, _ := filepath.Glob(filepath.Join(path, "*.param"))
+		if len(params) == 0 {
+			return fmt.Errorf("缺少 .param 模型结构文件")
+		}
+		for _, param := range params {
+			networks = append(networks, strings.TrimSuffix(filepath.Base(param), ".param"))
+		}
+	}
+
+	for _, network := range networks {
+		if err := validateNetwork(path, network); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateNetwork 检查 network.param 是 ncnn 模型结构文件，且 network.bin 存在且非空
+func validateNetwork(dir, network string) error {
+	paramName, binName := network+".param", network+".bin"
+
+	f, err := os.Open(filepath.Join(dir, paramName))
+	if err != nil {
+		return fmt.Errorf("缺少 %s", paramName)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != ncnnParamMagic {
+		return fmt.Errorf("%s 不是有效的 ncnn 模型结构文件", paramName)
+	}
+
+	binInfo, err := os.Stat(filepath.Join(dir, binName))
+	if err != nil {
+		return fmt.Errorf("缺少 %s", binName)
+	}
+	if binInfo.Size() == 0 {
+		return fmt.Errorf("%s 为空文件", binName)
+	}
+	return nil
+}
+
+// findModel 按名称在已发现的模型中查找
+func findModel(models []EngineModel, name string) (EngineModel, bool) {
+	for _, model := range models {
+		if model.Name == name {
+			return model, true
+		}
+	}
+	return EngineModel{}, false
+}
+
+// validModelNames 返回可用模型的名称，供界面选择
+func validModelNames(models []EngineModel) []string {
+	var names []string
+	for _, model := range models {
+		if model.Valid() {
+			names = append(names, model.Name)
+		}
+	}
+	return names
+}
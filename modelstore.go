@@ -0,0 +1,325 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const modelMetaFile = ".fps2x-model.json"
+
+// modelMeta 是导入模型时写入模型目录的元数据
+type modelMeta struct {
+	Origin     string    `json:"origin"` // 导入时的压缩包或文件夹路径
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// getUserModelsDir 返回用户模型目录，导入的模型保存在这里
+func getUserModelsDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "fps2x", "models"), nil
+}
+
+// readModelMeta 读取导入时记录的元数据，内置模型没有该文件
+func readModelMeta(path string) (modelMeta, bool) {
+	data, err := os.ReadFile(filepath.Join(path, modelMetaFile))
+	if err != nil {
+		return modelMeta{}, false
+	}
+	var meta modelMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return modelMeta{}, false
+	}
+	return meta, true
+}
+
+// importModel 从 .zip、.tar.gz 压缩包或文件夹导入模型到用户模型目录
+// 压缩包中可以包含多个模型（例如 RIFE 官方发布包），所有完整的模型都会被导入
+func importModel(src string) ([]EngineModel, error) {
+	modelsDir, err := getUserModelsDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(modelsDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建模型目录失败: %w", err)
+	}
+
+	// 先解压到同一磁盘上的暂存目录，校验通过后再重命名到位
+	staging, err := os.MkdirTemp(modelsDir, ".import-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建暂存目录失败: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	extracted := filepath.Join(staging, "extracted")
+	lower := strings.ToLower(src)
+	switch info, statErr := os.Stat(src); {
+	case statErr != nil:
+		return nil, fmt.Errorf("无法读取 %s: %w", src, statErr)
+	case info.IsDir():
+		err = copyDir(src, extracted)
+	case strings.HasSuffix(lower, ".zip"):
+		err = extractZip(src, extracted)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		err = extractTarGz(src, extracted)
+	default:
+		return nil, fmt.Errorf("不支持的模型格式，请选择 .zip、.tar.gz 或文件夹")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解压模型失败: %w", err)
+	}
+
+	candidates, err := findModelDirs(extracted)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("没有找到包含 .param 模型结构文件的目录")
+	}
+	// 根目录直接就是模型文件时，用压缩包或文件夹的名称作为模型名
+	if len(candidates) == 1 && candidates[0] == extracted {
+		dir := filepath.Join(staging, modelDirName(archiveBaseName(src)))
+		if err := os.Rename(extracted, dir); err != nil {
+			return nil, err
+		}
+		candidates = []string{dir}
+	}
+
+	var imported []EngineModel
+	var problems []string
+	for _, dir := range candidates {
+		name := modelDirName(filepath.Base(dir))
+		engine, _ := engineForModel(name)
+		if err := validateModelDir(engine, dir); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+
+		meta, err := json.MarshalIndent(modelMeta{Origin: absPath(src), ImportedAt: time.Now()}, "", "  ")
+		if err != nil {
+			return imported, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, modelMetaFile), meta, 0644); err != nil {
+			return imported, fmt.Errorf("写入模型信息失败: %w", err)
+		}
+
+		// 同名的用户模型直接替换
+		dst := filepath.Join(modelsDir, name)
+		if err := os.RemoveAll(dst); err != nil {
+			return imported, fmt.Errorf("替换模型 %s 失败: %w", name, err)
+		}
+		if err := os.Rename(dir, dst); err != nil {
+			return imported, fmt.Errorf("保存模型 %s 失败: %w", name, err)
+		}
+		imported = append(imported, inspectModel(engine, dst, SourceUser))
+	}
+
+	if len(imported) == 0 {
+		return nil, fmt.Errorf("没有可导入的完整模型:\n%s", strings.Join(problems, "\n"))
+	}
+	return imported, nil
+}
+
+// removeModel 删除用户导入的模型，binaries 目录和插帧程序所在目录中的模型不能删除
+func removeModel(name string) error {
+	modelsDir, err := getUserModelsDir()
+	if err != nil {
+		return err
+	}
+	if _, ok := engineForModel(name); !ok || name != filepath.Base(name) {
+		return fmt.Errorf("无效的模型名称: %s", name)
+	}
+
+	path := filepath.Join(modelsDir, name)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("模型 %s 不是用户导入的模型", name)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("删除模型失败: %w", err)
+	}
+	return nil
+}
+
+// archiveBaseName 去掉压缩包扩展名，返回文件或文件夹的名称
+func archiveBaseName(src string) string {
+	name := filepath.Base(src)
+	for _, ext := range []string{".zip", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// modelDirName 保证模型目录名带有某个引擎的前缀，否则不会被发现
+// 无法判断所属引擎的模型按 RIFE 模型处理
+func modelDirName(name string) string {
+	if _, ok := engineForModel(name); ok {
+		return name
+	}
+	engine, _ := findEngine(defaultEngineName)
+	return engine.ModelPrefix + name
+}
+
+// findModelDirs 返回 root 下所有包含 .param 模型结构文件的目录
+func findModelDirs(root string) ([]string, error) {
+	var dirs []string
+	seen := map[string]bool{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if dir := filepath.Dir(path); !d.IsDir() && filepath.Ext(path) == ".param" && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// safeJoin 拼接压缩包内的路径，拒绝跳出目标目录的条目
+func safeJoin(root, name string) (string, error) {
+	path := filepath.Join(root, filepath.FromSlash(name))
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("压缩包中包含非法路径: %s", name)
+	}
+	return path, nil
+}
+
+func extractZip(src, dst string) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, file := range r.File {
+		path, err := safeJoin(dst, file.Name)
+		if err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		in, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFileFrom(path, in)
+		in.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTarGz(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path, err := safeJoin(dst, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFileFrom(path, tr); err != nil {
+				return err
+			}
+		}
+		// 链接等其他类型的条目不属于模型文件，直接跳过
+	}
+}
+
+// copyDir 复制目录中的普通文件
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		return writeFileFrom(target, in)
+	})
+}
+
+func writeFileFrom(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSafeJoin(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "models")
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "rife-v4.6/flownet.bin", want: filepath.Join(root, "rife-v4.6", "flownet.bin")},
+		{name: "rife-v4.6/", want: filepath.Join(root, "rife-v4.6")},
+		{name: "a/../b.param", want: filepath.Join(root, "b.param")},
+		{name: "..hidden/flownet.bin", want: filepath.Join(root, "..hidden", "flownet.bin")},
+		{name: "/etc/passwd", want: filepath.Join(root, "etc", "passwd")},
+		{name: "../evil", wantErr: true},
+		{name: "a/../../evil", wantErr: true},
+		{name: "..", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := safeJoin(root, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("safeJoin(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("safeJoin(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
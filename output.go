@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// 输出目录中的临时文件和锁文件前缀（隐藏文件，避免被误认为结果）
+const (
+	outputTempPrefix = ".fps2x-tmp-"
+	outputLockPrefix = ".fps2x-lock-"
+)
+
+// outputTarget 表示一次最终输出的写入目标
+// ffmpeg 先写入同目录下的临时文件，成功后再重命名为最终文件名，
+// 这样中途失败或被中断时目标位置不会出现不完整的视频
+type outputTarget struct {
+	Path     string // 最终输出路径
+	TempPath string // ffmpeg 实际写入的临时路径
+	lockPath string
+}
+
+func getOutputDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, "Downloads"), nil
+}
+
+// acquireOutput 为输出路径加锁，防止两个任务同时写同一个文件
+func acquireOutput(outputPath string) (*outputTarget, error) {
+	dir, name := filepath.Split(outputPath)
+	lockPath := filepath.Join(dir, outputLockPrefix+name)
+
+	// 最多重试一次：第一次失败可能是崩溃残留的锁
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d", os.Getpid())
+			f.Close()
+			return &outputTarget{
+				Path:     outputPath,
+				TempPath: filepath.Join(dir, fmt.Sprintf("%s%d-%s", outputTempPrefix, os.Getpid(), name)),
+				lockPath: lockPath,
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("创建输出锁失败: %w", err)
+		}
+
+		if pid := readPIDFile(lockPath); pid > 0 && processAlive(pid) {
+			return nil, fmt.Errorf("%s 正在被另一个任务（PID %d）写入", name, pid)
+		}
+		os.Remove(lockPath)
+	}
+
+	return nil, fmt.Errorf("无法锁定输出文件 %s", name)
+}
+
+// Commit 将临时文件重命名为最终文件
+func (t *outputTarget) Commit() error {
+	if err := os.Rename(t.TempPath, t.Path); err != nil {
+		return fmt.Errorf("重命名输出文件失败: %w", err)
+	}
+	return nil
+}
+
+// Release 删除未提交的临时文件并释放锁，可在 Commit 之后安全调用
+func (t *outputTarget) Release() {
+	os.Remove(t.TempPath)
+	os.Remove(t.lockPath)
+}
+
+// cleanupStaleOutputs 删除输出目录中崩溃或中断遗留的临时文件和锁
+// 仍在运行的进程持有的文件会被保留
+func cleanupStaleOutputs(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+
+		var pid int
+		switch {
+		case strings.HasPrefix(name, outputTempPrefix):
+			pidStr, _, _ := strings.Cut(strings.TrimPrefix(name, outputTempPrefix), "-")
+			pid, _ = strconv.Atoi(pidStr)
+		case strings.HasPrefix(name, outputLockPrefix):
+			pid = readPIDFile(path)
+		default:
+			continue
+		}
+
+		if pid > 0 && processAlive(pid) {
+			continue
+		}
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+func readPIDFile(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+// processAlive 判断进程是否仍在运行
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Windows 上 FindProcess 成功即表示进程存在
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ffmpegThreadsPerSegment = 4 // 每个并行 ffmpeg 进程使用的线程数
+	maxParallelSegments     = 8
+)
+
+// parallelSegmentCount 根据 CPU 线程预算计算并行的 ffmpeg 进程数
+// 只有最终编码器为 libx264（CPU 编码）时才值得并行
+func parallelSegmentCount(codec string, width, height int) int {
+	if codec != "libx264" {
+		return 1
+	}
+	return min(max(cpuThreadBudget(width, height)/ffmpegThreadsPerSegment, 1), maxParallelSegments)
+}
+
+// probeKeyframes 返回视频流所有关键帧的时间戳（秒），只读取数据包，不解码
+func probeKeyframes(ctx context.Context, ffprobePath, inputPath string) ([]float64, error) {
+	cmd := exec.CommandContext(ctx, ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "packet=pts_time,flags",
+		"-of", "csv=p=0",
+		inputPath,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("执行 ffprobe 失败: %w", err)
+	}
+
+	var keyframes []float64
+	for _, line := range strings.Split(string(output), "\n") {
+		ptsStr, flags, ok := strings.Cut(strings.TrimSpace(line), ",")
+		if !ok || !strings.Contains(flags, "K") {
+			continue
+		}
+		// 没有时间戳的数据包为 N/A，跳过
+		if pts, err := strconv.ParseFloat(ptsStr, 64); err == nil {
+			keyframes = append(keyframes, pts)
+		}
+	}
+	sort.Float64s(keyframes)
+	return keyframes, nil
+}
+
+// frameSegment 是一段按关键帧切分的时间范围，时间相对视频第一帧（秒），end 为 0 表示直到结尾
+type frameSegment struct {
+	start float64
+	end   float64
+}
+
+// keyframeSegments 将视频按关键帧切成最多 n 段时长接近的片段，相邻片段首尾相接
+func keyframeSegments(keyframes []float64, duration float64, n int) []frameSegment {
+	if len(keyframes) == 0 {
+		return []frameSegment{{}}
+	}
+	origin := keyframes[0]
+
+	segments := []frameSegment{{}}
+	for i := 1; i < n; i++ {
+		target := origin + duration*float64(i)/float64(n)
+		index := sort.SearchFloat64s(keyframes, target)
+		if index >= len(keyframes) {
+			break
+		}
+		start := keyframes[index] - origin
+		if start <= segments[len(segments)-1].start {
+			continue
+		}
+		segments[len(segments)-1].end = start
+		segments = append(segments, frameSegment{start: start})
+	}
+	return segments
+}
+
+// args 返回拆出片段帧所需的定位参数
+// 片段边界都是关键帧，定位和截止都提前半帧，使边界上的关键帧恰好属于后一段，不受浮点误差影响；
+// 输入和输出的时间都相对容器的起始时间，需要加上视频流的起始偏移
+func (seg frameSegment) args(startOffset, fps float64) (input, output []string) {
+	half := 0.5 / fps
+	begin := -startOffset // 输出时间轴的零点，相对视频第一帧
+	if seg.start > 0 {
+		begin = seg.start - half
+		input = []string{"-ss", fmt.Sprintf("%.6f", startOffset+begin)}
+	}
+	if seg.end > 0 {
+		output = []string{"-t", fmt.Sprintf("%.6f", seg.end-half-begin)}
+	}
+	return input, output
+}
+
+// extractFramesParallel 按关键帧把视频切成多段，由多个 ffmpeg 进程同时拆帧
+// 每段写入独立的子目录，全部完成后按顺序合并到 in 目录并连续编号，片段之间不会互相覆盖
+func extractFramesParallel(ctx context.Context, job *videoJob, duration float64, workers int) error {
+	keyframes, err := probeKeyframes(ctx, job.paths.FFprobe, job.inputPath)
+	if err != nil {
+		return err
+	}
+	segments := keyframeSegments(keyframes, duration, workers)
+	inDir := filepath.Join(job.workDir, "in")
+
+	partDirs := make([]string, len(segments))
+	for i := range segments {
+		partDirs[i] = filepath.Join(job.workDir, fmt.Sprintf("in_part_%02d", i))
+		if err := os.MkdirAll(partDirs[i], 0755); err != nil {
+			return fmt.Errorf("创建拆帧目录失败: %w", err)
+		}
+	}
+	defer func() {
+		for _, dir := range partDirs {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	err = runParallel(ctx, len(segments), func(ctx context.Context, i int) error {
+		input, output := segments[i].args(job.startOffset, job.fpsOrigin.Float())
+		args := append([]string{"-y"}, input...)
+		args = append(args, "-i", job.inputPath, "-threads", fmt.Sprintf("%d", ffmpegThreadsPerSegment))
+		args = append(args, output...)
+		args = append(args, job.format.EncodeArgs()...)
+		return runCommand(ctx, job.paths.FFmpeg, append(args, job.format.Pattern(partDirs[i])))
+	})
+	if err != nil {
+		return err
+	}
+	return concatFrameDirs(inDir, partDirs)
+}
+
+// encodeFramesParallel 将帧序列平均分给多个 ffmpeg 进程编码，再无损拼接并封装音频
+func encodeFramesParallel(ctx context.Context, job *videoJob, frameDir string, workers int) error {
+	totalFrames, err := countFiles(frameDir)
+	if err != nil {
+		return err
+	}
+	if totalFrames == 0 {
+		return fmt.Errorf("没有可编码的帧")
+	}
+	workers = min(workers, totalFrames)
+
+	segDir := filepath.Join(job.workDir, "segments")
+	if err := os.MkdirAll(segDir, 0755); err != nil {
+		return fmt.Errorf("创建片段目录失败: %w", err)
+	}
+
+	segments := make([]string, workers)
+	err = runParallel(ctx, workers, func(ctx context.Context, i int) error {
+		start := totalFrames * i / workers
+		end := totalFrames * (i + 1) / workers
+		segments[i] = filepath.Join(segDir, fmt.Sprintf("%04d.mp4", i))
+		return runCommand(ctx, job.paths.FFmpeg, []string{
+			"-y",
+			"-framerate", job.fpsTarget.String(),
+			"-start_number", fmt.Sprintf("%d", start+1),
+			"-i", job.format.Pattern(frameDir),
+			"-frames:v", fmt.Sprintf("%d", end-start),
+			"-c:v", "libx264",
+			"-b:v", "15M",
+			"-pix_fmt", "yuv420p",
+			"-threads", fmt.Sprintf("%d", ffmpegThreadsPerSegment),
+			segments[i],
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	listPath := filepath.Join(job.workDir, "segments.txt")
+	if err := writeConcatList(listPath, segments); err != nil {
+		return err
+	}
+	return runCommand(ctx, job.paths.FFmpeg, []string{
+		"-y",
+		"-f", "concat", "-safe", "0", "-i", listPath,
+		"-i", job.audioPath,
+		"-c:v", "copy",
+		"-c:a", "copy",
+		"-shortest", job.output.TempPath,
+	})
+}
+
+// runParallel 并发执行 n 个任务，任一任务失败时中止其余任务并返回第一个错误
+func runParallel(ctx context.Context, n int, task func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := task(ctx, i); err != nil {
+				cancel(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return context.Cause(ctx)
+}
+
+// concatFrameDirs 按顺序把各目录中的帧移入 dst，并从 1 开始连续编号
+func concatFrameDirs(dst string, dirs []string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	next := 1
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			want := fmt.Sprintf("%08d%s", next, filepath.Ext(entry.Name()))
+			if err := os.Rename(filepath.Join(dir, entry.Name()), filepath.Join(dst, want)); err != nil {
+				return fmt.Errorf("合并拆帧结果失败: %w", err)
+			}
+			next++
+		}
+	}
+	return nil
+}
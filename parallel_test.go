@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestKeyframeSegments(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyframes []float64
+		duration  float64
+		n         int
+		want      []frameSegment
+	}{
+		{
+			name: "没有关键帧信息时不切分",
+			n:    4,
+			want: []frameSegment{{}},
+		},
+		{
+			name:      "按关键帧均分",
+			keyframes: []float64{0, 2, 4, 6, 8},
+			duration:  10,
+			n:         2,
+			want:      []frameSegment{{start: 0, end: 6}, {start: 6}},
+		},
+		{
+			name:      "关键帧时间从非零开始时以第一帧为原点",
+			keyframes: []float64{1.5, 3.5, 5.5, 7.5},
+			duration:  8,
+			n:         2,
+			want:      []frameSegment{{start: 0, end: 4}, {start: 4}},
+		},
+		{
+			name:      "关键帧过少时合并到同一段",
+			keyframes: []float64{0, 9},
+			duration:  10,
+			n:         4,
+			want:      []frameSegment{{start: 0, end: 9}, {start: 9}},
+		},
+		{
+			name:      "目标时间之后没有关键帧时停止切分",
+			keyframes: []float64{0, 1},
+			duration:  10,
+			n:         3,
+			want:      []frameSegment{{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keyframeSegments(tt.keyframes, tt.duration, tt.n)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keyframeSegments() = %+v, want %+v", got, tt.want)
+			}
+			// 相邻片段必须首尾相接，不能重叠或留下空隙
+			for i := 1; i < len(got); i++ {
+				if got[i-1].end != got[i].start {
+					t.Errorf("片段 %d 结束于 %v，片段 %d 开始于 %v", i-1, got[i-1].end, i, got[i].start)
+				}
+			}
+		})
+	}
+}
+
+func TestFrameSegmentArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		seg           frameSegment
+		offset        float64
+		input, output []string
+	}{
+		{"整段", frameSegment{}, 0, nil, nil},
+		{"第一段", frameSegment{end: 6}, 0, nil, []string{"-t", "5.987500"}},
+		{"中间段", frameSegment{start: 6, end: 12}, 0, []string{"-ss", "5.987500"}, []string{"-t", "6.000000"}},
+		{"最后一段", frameSegment{start: 12}, 0, []string{"-ss", "11.987500"}, nil},
+		{"视频流有起始偏移", frameSegment{start: 6, end: 12}, 0.5, []string{"-ss", "6.487500"}, []string{"-t", "6.000000"}},
+		{"有起始偏移的第一段", frameSegment{end: 6}, 0.5, nil, []string{"-t", "6.487500"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, output := tt.seg.args(tt.offset, 40)
+			if !slices.Equal(input, tt.input) || !slices.Equal(output, tt.output) {
+				t.Errorf("args() = %v %v, want %v %v", input, output, tt.input, tt.output)
+			}
+		})
+	}
+}
+
+func TestConcatFrameDirs(t *testing.T) {
+	root := t.TempDir()
+	dst := filepath.Join(root, "in")
+	// 每段都从 00000001 开始编号，第二段为空
+	parts := map[string][]string{
+		"part_00": {"00000001.png", "00000002.png", "00000003.png"},
+		"part_01": nil,
+		"part_02": {"00000001.png", "00000002.png"},
+	}
+	var dirs []string
+	for _, name := range []string{"part_00", "part_01", "part_02"} {
+		dir := filepath.Join(root, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range parts[name] {
+			if err := os.WriteFile(filepath.Join(dir, file), []byte(name+"/"+file), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+
+	if err := concatFrameDirs(dst, dirs); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"00000001.png": "part_00/00000001.png",
+		"00000002.png": "part_00/00000002.png",
+		"00000003.png": "part_00/00000003.png",
+		"00000004.png": "part_02/00000001.png",
+		"00000005.png": "part_02/00000002.png",
+	}
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("合并后有 %d 帧，want %d", len(entries), len(want))
+	}
+	for name, source := range want {
+		data, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != source {
+			t.Errorf("%s 来自 %s，want %s", name, data, source)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import "fmt"
+
+// Preset 是一组处理设置，可以在界面中一键切换
+// 留空的字段在应用时恢复为默认值
+type Preset struct {
+	Name         string `json:"name"`
+	Mode         string `json:"mode"` // "2x"、"3x" 等倍数或 "60fps"、"59.94fps" 等固定帧率，见 outputModes
+	Engine       string `json:"engine,omitempty"`
+	Model        string `json:"model,omitempty"`
+	FrameFormat  string `json:"frame_format,omitempty"`
+	FrameQuality int    `json:"frame_quality,omitempty"`
+	Pipeline     bool   `json:"pipeline,omitempty"`
+	EngineTuning
+	QualityModes
+}
+
+// 内置预设，不可修改或删除
+var builtinPresets = []Preset{
+	{Name: "默认", Mode: "2x"},
+	{Name: "通用 60 帧", Mode: "60fps"},
+	{Name: "省空间（JPG 流水线）", Mode: "2x", FrameFormat: "jpg", FrameQuality: 95, Pipeline: true},
+	{Name: "最高画质（TTA，很慢）", Mode: "2x", QualityModes: QualityModes{SpatialTTA: true, TemporalTTA: true}},
+}
+
+// allPresets 返回内置预设和用户预设
+func (c *Config) allPresets() []Preset {
+	presets := append([]Preset{}, builtinPresets...)
+	return append(presets, c.Presets...)
+}
+
+func (c *Config) findPreset(name string) (Preset, bool) {
+	for _, preset := range c.allPresets() {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return Preset{}, false
+}
+
+func isBuiltinPreset(name string) bool {
+	for _, preset := range builtinPresets {
+		if preset.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPreset 将预设中的设置写入配置，输出模式由调用方处理
+func (c *Config) applyPreset(preset Preset) {
+	c.Engine = preset.Engine
+	c.Model = preset.Model
+	c.FrameFormat = preset.FrameFormat
+	c.FrameQuality = preset.FrameQuality
+	c.Pipeline = preset.Pipeline
+	c.EngineTuning = preset.EngineTuning
+	c.QualityModes = preset.QualityModes
+}
+
+// currentPreset 用当前配置和输出模式生成预设
+func (c *Config) currentPreset(name, mode string) Preset {
+	return Preset{
+		Name:         name,
+		Mode:         mode,
+		Engine:       c.Engine,
+		Model:        c.Model,
+		FrameFormat:  c.FrameFormat,
+		FrameQuality: c.FrameQuality,
+		Pipeline:     c.Pipeline,
+		EngineTuning: c.EngineTuning,
+		QualityModes: c.QualityModes,
+	}
+}
+
+// savePreset 保存用户预设，同名时覆盖
+func (c *Config) savePreset(preset Preset) error {
+	if preset.Name == "" {
+		return fmt.Errorf("预设名称不能为空")
+	}
+	if isBuiltinPreset(preset.Name) {
+		return fmt.Errorf("不能覆盖内置预设: %s", preset.Name)
+	}
+	for i := range c.Presets {
+		if c.Presets[i].Name == preset.Name {
+			c.Presets[i] = preset
+			return nil
+		}
+	}
+	c.Presets = append(c.Presets, preset)
+	return nil
+}
+
+// deletePreset 删除用户预设
+func (c *Config) deletePreset(name string) error {
+	if isBuiltinPreset(name) {
+		return fmt.Errorf("不能删除内置预设: %s", name)
+	}
+	for i := range c.Presets {
+		if c.Presets[i].Name == name {
+			c.Presets = append(c.Presets[:i], c.Presets[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("预设不存在: %s", name)
+}
+
+// presetNames 返回所有预设名称，供界面选择
+func (c *Config) presetNames() []string {
+	var names []string
+	for _, preset := range c.allPresets() {
+		names = append(names, preset.Name)
+	}
+	return names
+}
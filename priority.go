@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 后台优先级下子进程的 CPU 优先级（nice 值，越大越低）
+const backgroundNice = 10
+
+// newCommand 创建子进程命令，ffmpeg 按设置限制编码线程数
+func newCommand(ctx context.Context, command string, args []string) *exec.Cmd {
+	if isFFmpegCommand(command) && appConfig.FFmpegThreads > 0 {
+		args = limitFFmpegThreads(args, appConfig.FFmpegThreads)
+	}
+	return exec.CommandContext(ctx, command, args...)
+}
+
+// startCommand 启动子进程，开启后台优先级时降低其 CPU 和 I/O 优先级
+func startCommand(cmd *exec.Cmd) error {
+	if !appConfig.BackgroundPriority {
+		return cmd.Start()
+	}
+	return startLowPriority(cmd)
+}
+
+func isFFmpegCommand(command string) bool {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(command)), ".exe")
+	return name == "ffmpeg"
+}
+
+// limitFFmpegThreads 在输出文件（最后一个参数）之前加入 -threads，并限制滤镜线程数
+// fps2x 调用 ffmpeg 时最后一个参数总是输出路径
+func limitFFmpegThreads(args []string, threads int) []string {
+	if len(args) == 0 {
+		return args
+	}
+	n := strconv.Itoa(threads)
+	limited := append([]string{"-filter_threads", n}, args[:len(args)-1]...)
+	return append(limited, "-threads", n, args[len(args)-1])
+}
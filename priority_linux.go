@@ -0,0 +1,33 @@
+//go:build linux
+
+package main
+
+import (
+	"os/exec"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// ioprio_set 的参数：对单个线程设置 best-effort 类中最低的 I/O 优先级
+const (
+	ioprioWhoProcess  = 1
+	ioprioClassBE     = 2
+	ioprioClassShift  = 13
+	ioprioLowestLevel = 7
+)
+
+// startLowPriority 在单独的系统线程上降低 nice 值和 I/O 优先级后启动子进程
+// Linux 上优先级按线程生效，fork 出的子进程继承调用线程的设置；
+// 线程不解锁，goroutine 结束时随之退出，不会影响本进程的其他 goroutine
+func startLowPriority(cmd *exec.Cmd) error {
+	errc := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		tid := unix.Gettid()
+		unix.Setpriority(unix.PRIO_PROCESS, tid, backgroundNice)
+		unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassBE<<ioprioClassShift|ioprioLowestLevel)
+		errc <- cmd.Start()
+	}()
+	return <-errc
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"os/exec"
+
+	"golang.org/x/sys/unix"
+)
+
+// startLowPriority 启动后降低子进程的 nice 值
+// macOS 等系统上 nice 值按进程生效，没有可移植的 I/O 优先级接口
+func startLowPriority(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	unix.Setpriority(unix.PRIO_PROCESS, cmd.Process.Pid, backgroundNice)
+	return nil
+}
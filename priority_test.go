@@ -0,0 +1,42 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLimitFFmpegThreads(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "输出路径之前加入 -threads",
+			args: []string{"-y", "-i", "in.mp4", "-c:v", "libx264", "out.mp4"},
+			want: []string{"-filter_threads", "2", "-y", "-i", "in.mp4", "-c:v", "libx264", "-threads", "2", "out.mp4"},
+		},
+		{
+			name: "只有输出路径",
+			args: []string{"out.mp4"},
+			want: []string{"-filter_threads", "2", "-threads", "2", "out.mp4"},
+		},
+		{
+			name: "没有参数",
+			args: nil,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := slices.Clone(tt.args)
+			if got := limitFFmpegThreads(tt.args, 2); !slices.Equal(got, tt.want) {
+				t.Errorf("limitFFmpegThreads() = %v, want %v", got, tt.want)
+			}
+			// 不能修改调用方的参数
+			if !slices.Equal(tt.args, original) {
+				t.Errorf("参数被修改为 %v", tt.args)
+			}
+		})
+	}
+}
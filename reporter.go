@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// jobStep 是处理流程中显示进度的三个步骤
+type jobStep int
+
+const (
+	stepExtract jobStep = iota
+	stepInterp
+	stepMerge
+)
+
+// jobReporter 接收任务的进度和结果，图形界面和命令行批量处理分别实现
+type jobReporter interface {
+	Progress(text string, percent float64)
+	Step(step jobStep, status ProcessingStep, name string)
+	StepProgress(step jobStep, progress float64)
+	Log(text string)
+	Error(message string)
+	Completed(outputPath string)
+}
+
+// guiReporter 把进度显示在主窗口中，同一时间只有一个界面任务
+type guiReporter struct{}
+
+func (guiReporter) Progress(text string, percent float64) {
+	updateProgress(text, percent)
+}
+
+func (guiReporter) Step(step jobStep, status ProcessingStep, name string) {
+	label, _ := stepWidgets(step)
+	updateStep(label, status, name)
+}
+
+func (guiReporter) StepProgress(step jobStep, progress float64) {
+	_, bar := stepWidgets(step)
+	updateStepProgress(bar, progress)
+}
+
+func (guiReporter) Log(text string) {
+	logJob(text)
+}
+
+func (guiReporter) Error(message string) {
+	showError(message)
+}
+
+func (guiReporter) Completed(outputPath string) {
+	showCompleted(outputPath)
+}
+
+func stepWidgets(step jobStep) (*widget.Label, *widget.ProgressBar) {
+	switch step {
+	case stepExtract:
+		return stepExtractLabel, stepExtractProgress
+	case stepInterp:
+		return stepInterpLabel, stepInterpProgress
+	default:
+		return stepMergeLabel, stepMergeProgress
+	}
+}
+
+// consoleReporter 把任务进度逐行输出到终端，每行以任务名开头，便于区分同时运行的任务
+type consoleReporter struct {
+	name string
+	out  io.Writer
+	mu   *sync.Mutex // 多个任务共用，避免输出交错
+
+	failed   bool
+	output   string
+	lastText string
+}
+
+func (r *consoleReporter) printf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.out, "[%s] %s\n", r.name, fmt.Sprintf(format, args...))
+}
+
+func (r *consoleReporter) Progress(text string, percent float64) {
+	if text == r.lastText {
+		return
+	}
+	r.lastText = text
+	r.printf("%3.0f%% %s", percent, text)
+}
+
+func (r *consoleReporter) Step(step jobStep, status ProcessingStep, name string) {
+	switch status {
+	case StepCompleted:
+		r.printf("%s完成", name)
+	case StepError:
+		r.printf("%s失败", name)
+	}
+}
+
+func (r *consoleReporter) StepProgress(step jobStep, progress float64) {}
+
+func (r *consoleReporter) Log(text string) {
+	r.printf("%s", text)
+}
+
+func (r *consoleReporter) Error(message string) {
+	r.failed = true
+	r.printf("错误: %s", message)
+}
+
+func (r *consoleReporter) Completed(outputPath string) {
+	r.output = outputPath
+	r.printf("处理完成，已保存至 %s", outputPath)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var rifeVersionPattern = regexp.MustCompile(`^rife-v(\d+)`)
+
+// 超过1080p算高分辨率
+func isHighResolution(width, height int) bool {
+	return width*height > 1920*1080
+}
+
+// 接近或超过4K
+func is4KResolution(width, height int) bool {
+	return float64(width*height) > 3840*2160*0.9
+}
+
+// rifeThreads 根据分辨率计算 RIFE 的 -j load:proc:save 线程数
+func rifeThreads(width, height int) (loadThreads, procThreads, saveThreads int) {
+	// 自动计算最佳线程数（保留足够核心给系统）
+	if is4KResolution(width, height) {
+		// 4K 视频：保守策略，避免显存溢出
+		// 只用少量线程，避免显存不足导致 swap
+		return 2, 4, 2
+	}
+
+	if isHighResolution(width, height) {
+		// 2K/1440p等高分辨率：中等策略
+		optimalThreads := min(cpuThreadBudget(width, height), 12)
+		return optimalThreads, optimalThreads * 2, optimalThreads
+	}
+
+	// 1080p及以下：激进策略，最大化性能
+	optimalThreads := min(cpuThreadBudget(width, height), 16)
+	return optimalThreads, optimalThreads * 4, optimalThreads
+}
+
+// cpuThreadBudget 返回保留系统核心后可用于处理的 CPU 线程数
+// 高分辨率时多保留一个核心
+func cpuThreadBudget(width, height int) int {
+	reservedCPU := 1
+	if isHighResolution(width, height) {
+		reservedCPU = 2
+	}
+	return max(runtime.NumCPU()-reservedCPU, 1)
+}
+
+// EngineTuning 是插帧程序的高级参数，留空表示自动
+// 设置和预设共用，JSON 中与其他字段平铺
+type EngineTuning struct {
+	Threads  string `json:"threads,omitempty"`   // -j load:proc:save，如 4:8:4
+	TileSize int    `json:"tile_size,omitempty"` // -t 分块大小，须为 32 的倍数
+	GPU      string `json:"gpu,omitempty"`       // -g 显卡编号，多块显卡用逗号分隔，-1 表示使用 CPU
+}
+
+// effectiveTuning 是实际传给插帧程序的参数
+type effectiveTuning struct {
+	LoadThreads, ProcThreads, SaveThreads int
+	TileSize                              int    // 0 表示由插帧程序自动选择
+	GPU                                   string // 为空表示由插帧程序自动选择
+	Devices                               int    // 指定的设备数量，多于 1 时处理线程按设备拆分
+	Auto                                  bool   // 线程数由分辨率自动计算
+	Profile                               bool   // 线程数来自基准测试档案
+}
+
+// parseThreads 解析 load:proc:save 格式的线程数
+func parseThreads(text string) (loadThreads, procThreads, saveThreads int, err error) {
+	parts := strings.Split(text, ":")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("线程数格式应为 load:proc:save，如 4:8:4")
+	}
+	var values [3]int
+	for i, part := range parts {
+		values[i], err = strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || values[i] < 1 {
+			return 0, 0, 0, fmt.Errorf("线程数必须是正整数: %s", text)
+		}
+	}
+	return values[0], values[1], values[2], nil
+}
+
+// validate 检查高级参数的格式，空值和 auto 表示自动
+func (t EngineTuning) validate() error {
+	if !isAutoValue(t.Threads) {
+		if _, _, _, err := parseThreads(t.Threads); err != nil {
+			return err
+		}
+	}
+	if t.TileSize < 0 || t.TileSize%32 != 0 {
+		return fmt.Errorf("分块大小必须是 32 的倍数: %d", t.TileSize)
+	}
+	_, err := parseGPUSetting(t.GPU)
+	return err
+}
+
+// resolve 根据分辨率计算实际参数，线程数为自动时使用引擎的 autoThreads 策略
+// autoThreads 为空时不指定线程数，由插帧程序使用自身的默认值
+func (t EngineTuning) resolve(width, height int, autoThreads func(width, height int) (int, int, int)) effectiveTuning {
+	effective := effectiveTuning{TileSize: t.TileSize}
+	if ids, err := parseGPUSetting(t.GPU); err == nil && len(ids) > 0 {
+		effective.GPU = strings.ReplaceAll(strings.TrimSpace(t.GPU), " ", "")
+		effective.Devices = len(ids)
+	}
+	var err error
+	if !isAutoValue(t.Threads) {
+		effective.LoadThreads, effective.ProcThreads, effective.SaveThreads, err = parseThreads(t.Threads)
+	}
+	if isAutoValue(t.Threads) || err != nil {
+		effective.LoadThreads, effective.ProcThreads, effective.SaveThreads = 0, 0, 0
+		if autoThreads != nil {
+			effective.LoadThreads, effective.ProcThreads, effective.SaveThreads = autoThreads(width, height)
+		}
+		effective.Auto = true
+	}
+	return effective
+}
+
+// ThreadsArg 返回 -j 参数的值，未指定线程数时为空
+// 使用多块显卡时插帧程序要求为每块显卡分别指定处理线程数，如 1:2,2:2
+func (e effectiveTuning) ThreadsArg() string {
+	if e.ProcThreads == 0 {
+		return ""
+	}
+	proc := strconv.Itoa(e.ProcThreads)
+	if e.Devices > 1 {
+		perDevice := strconv.Itoa(max((e.ProcThreads+e.Devices-1)/e.Devices, 1))
+		proc = strings.Repeat(perDevice+",", e.Devices-1) + perDevice
+	}
+	return fmt.Sprintf("%d:%s:%d", e.LoadThreads, proc, e.SaveThreads)
+}
+
+// TileArg 返回 -t 参数的值
+// 与 -j 的处理线程一样，使用多块显卡时需要为每块显卡分别指定，如 256,256
+func (e effectiveTuning) TileArg() string {
+	tile := strconv.Itoa(e.TileSize)
+	if e.Devices > 1 {
+		return strings.Repeat(tile+",", e.Devices-1) + tile
+	}
+	return tile
+}
+
+// String 返回适合写入任务日志的参数说明
+func (e effectiveTuning) String() string {
+	threads := "-j " + e.ThreadsArg()
+	if e.ProcThreads == 0 {
+		threads = "-j 程序默认"
+	} else if e.Auto {
+		threads += "（自动）"
+	} else if e.Profile {
+		threads += "（基准测试）"
+	}
+	tile, gpu := "自动", "自动"
+	if e.TileSize > 0 {
+		tile = e.TileArg()
+	}
+	if e.GPU != "" {
+		gpu = e.GPU
+	}
+	return fmt.Sprintf("%s  -t %s  -g %s", threads, tile, gpu)
+}
+
+// 画质模式带来的插帧耗时倍数（经验值）
+// 空间 TTA 对每帧的 8 种翻转/转置分别推理后取平均；时间 TTA 额外按倒序推理一次
+const (
+	spatialTTACost  = 8.0
+	temporalTTACost = 2.0
+)
+
+// QualityModes 是插帧程序的画质模式，设置和预设共用
+type QualityModes struct {
+	UHD         string `json:"uhd,omitempty"`          // auto/on/off，auto 表示 4K 视频自动开启
+	SpatialTTA  bool   `json:"spatial_tta,omitempty"`  // -x 空间 TTA
+	TemporalTTA bool   `json:"temporal_tta,omitempty"` // -z 时间 TTA
+}
+
+// 界面中 UHD 选项的显示名称，顺序与 uhdModeValues 对应
+var (
+	uhdModeLabels = []string{"自动（4K 开启）", "开启", "关闭"}
+	uhdModeValues = []string{"auto", "on", "off"}
+)
+
+// effectiveModes 是任务实际使用的画质模式，已排除引擎不支持的选项
+type effectiveModes struct {
+	UHD, SpatialTTA, TemporalTTA bool
+	Ignored                      []string // 引擎不支持而被忽略的选项
+}
+
+func (m QualityModes) validate() error {
+	if !isAutoValue(m.UHD) && m.UHD != "on" && m.UHD != "off" {
+		return fmt.Errorf("UHD 模式只能是 auto、on 或 off: %s", m.UHD)
+	}
+	return nil
+}
+
+// resolve 根据分辨率和引擎能力确定实际的画质模式
+func (m QualityModes) resolve(engine *interpolationEngine, width, height int) effectiveModes {
+	var effective effectiveModes
+	uhd := m.UHD == "on" || (isAutoValue(m.UHD) && is4KResolution(width, height))
+	for _, option := range []struct {
+		name      string
+		wanted    bool
+		supported bool
+		target    *bool
+	}{
+		{"UHD", uhd, engine.HasUHD, &effective.UHD},
+		{"空间 TTA", m.SpatialTTA, engine.HasSpatialTTA, &effective.SpatialTTA},
+		{"时间 TTA", m.TemporalTTA, engine.HasTemporalTTA, &effective.TemporalTTA},
+	} {
+		if !option.wanted {
+			continue
+		}
+		if option.supported {
+			*option.target = true
+		} else if option.name != "UHD" || m.UHD == "on" {
+			// 自动开启的 UHD 不支持时静默忽略
+			effective.Ignored = append(effective.Ignored, option.name)
+		}
+	}
+	return effective
+}
+
+// Args 返回画质模式对应的命令行参数
+func (e effectiveModes) Args() []string {
+	var args []string
+	if e.UHD {
+		args = append(args, "-u")
+	}
+	if e.SpatialTTA {
+		args = append(args, "-x")
+	}
+	if e.TemporalTTA {
+		args = append(args, "-z")
+	}
+	return args
+}
+
+// Cost 返回相对于默认模式的插帧耗时倍数
+func (e effectiveModes) Cost() float64 {
+	cost := 1.0
+	if e.SpatialTTA {
+		cost *= spatialTTACost
+	}
+	if e.TemporalTTA {
+		cost *= temporalTTACost
+	}
+	return cost
+}
+
+func (e effectiveModes) String() string {
+	var names []string
+	if e.UHD {
+		names = append(names, "UHD")
+	}
+	if e.SpatialTTA {
+		names = append(names, "空间 TTA")
+	}
+	if e.TemporalTTA {
+		names = append(names, "时间 TTA")
+	}
+	if len(names) == 0 {
+		return "默认"
+	}
+	return strings.Join(names, " + ")
+}
+
+func isAutoValue(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || strings.EqualFold(value, "auto")
+}
+
+// rifeSupportsTimestep 根据模型名判断是否支持任意时间步（rife-v4 及以上）
+func rifeSupportsTimestep(model string) bool {
+	match := rifeVersionPattern.FindStringSubmatch(model)
+	if match == nil {
+		return false
+	}
+	major, _ := strconv.Atoi(match[1])
+	return major >= 4
+}
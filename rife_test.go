@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestParseThreads(t *testing.T) {
+	tests := []struct {
+		text             string
+		load, proc, save int
+		wantErr          bool
+	}{
+		{text: "4:8:4", load: 4, proc: 8, save: 4},
+		{text: " 1 : 2 : 1 ", load: 1, proc: 2, save: 1},
+		{text: "4:8", wantErr: true},
+		{text: "4:8:4:4", wantErr: true},
+		{text: "0:8:4", wantErr: true},
+		{text: "4:-1:4", wantErr: true},
+		{text: "a:b:c", wantErr: true},
+		{text: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		load, proc, save, err := parseThreads(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseThreads(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if load != tt.load || proc != tt.proc || save != tt.save {
+			t.Errorf("parseThreads(%q) = %d:%d:%d, want %d:%d:%d", tt.text, load, proc, save, tt.load, tt.proc, tt.save)
+		}
+	}
+}
+
+func TestEffectiveTuningArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		tuning        EngineTuning
+		auto          func(width, height int) (int, int, int)
+		threads, tile string
+	}{
+		{"手动线程数", EngineTuning{Threads: "4:8:4", TileSize: 256}, nil, "4:8:4", "256"},
+		{"两块显卡拆分处理线程", EngineTuning{Threads: "2:6:2", TileSize: 256, GPU: "0,1"}, nil, "2:3,3:2", "256,256"},
+		{"处理线程不能整除时向上取整", EngineTuning{Threads: "1:5:1", TileSize: 128, GPU: "0, 1, 2"}, nil, "1:2,2,2:1", "128,128,128"},
+		{"CPU", EngineTuning{Threads: "1:2:1", TileSize: 64, GPU: "-1"}, nil, "1:2:1", "64"},
+		{"自动线程数", EngineTuning{}, func(int, int) (int, int, int) { return 2, 4, 2 }, "2:4:2", ""},
+		{"引擎没有自动策略时不传 -j", EngineTuning{Threads: "auto"}, nil, "", ""},
+		{"无效的线程数按自动处理", EngineTuning{Threads: "4:8"}, nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			effective := tt.tuning.resolve(1920, 1080, tt.auto)
+			if got := effective.ThreadsArg(); got != tt.threads {
+				t.Errorf("ThreadsArg() = %q, want %q", got, tt.threads)
+			}
+			// 分块大小为 0 时不传 -t，不检查 TileArg
+			if got := effective.TileArg(); tt.tile != "" && got != tt.tile {
+				t.Errorf("TileArg() = %q, want %q", got, tt.tile)
+			}
+		})
+	}
+}
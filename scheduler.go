@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// 资源槽位的默认值：显存通常只够一个插帧进程，编码占满 CPU 时再多开也不会更快
+const (
+	defaultGPUSlots    = 1
+	defaultEncodeSlots = 1
+)
+
+// SchedulerSettings 是同时处理多个任务时的资源限制，设置中与其他字段平铺
+type SchedulerSettings struct {
+	GPUSlots     int     `json:"gpu_slots,omitempty"`      // 同时运行的插帧阶段数，0 表示默认值
+	EncodeSlots  int     `json:"encode_slots,omitempty"`   // 同时运行的拆帧、编码阶段数，0 表示默认值
+	DiskBudgetGB float64 `json:"disk_budget_gb,omitempty"` // 所有任务临时文件的总预算（GB），0 表示不限制
+}
+
+func (s SchedulerSettings) validate() error {
+	if s.GPUSlots < 0 || s.EncodeSlots < 0 {
+		return fmt.Errorf("槽位数不能为负数")
+	}
+	if s.DiskBudgetGB < 0 {
+		return fmt.Errorf("磁盘预算不能为负数")
+	}
+	return nil
+}
+
+// slotKind 是流水线阶段占用的资源
+type slotKind int
+
+const (
+	slotGPU    slotKind = iota // 插帧
+	slotEncode                 // 拆帧、编码和 ffmpeg 补帧
+)
+
+// resourceScheduler 在多个任务之间分配资源槽位，让一个任务拆帧或编码时另一个任务可以插帧
+// 只有流水线模式会在持有编码槽位时等待插帧槽位，反过来不会发生，因此不会死锁
+type resourceScheduler struct {
+	config      SchedulerSettings
+	gpu, encode chan struct{}
+
+	mu          sync.Mutex
+	diskBudget  int64 // 0 表示不限制
+	diskUsed    int64
+	diskChanged chan struct{} // 释放磁盘预算时关闭，唤醒等待的任务
+}
+
+func newResourceScheduler(settings SchedulerSettings) *resourceScheduler {
+	gpuSlots, encodeSlots := settings.GPUSlots, settings.EncodeSlots
+	if gpuSlots <= 0 {
+		gpuSlots = defaultGPUSlots
+	}
+	if encodeSlots <= 0 {
+		encodeSlots = defaultEncodeSlots
+	}
+	return &resourceScheduler{
+		config:      settings,
+		gpu:         make(chan struct{}, gpuSlots),
+		encode:      make(chan struct{}, encodeSlots),
+		diskBudget:  int64(settings.DiskBudgetGB * 1024 * 1024 * 1024),
+		diskChanged: make(chan struct{}),
+	}
+}
+
+var (
+	schedulerMu  sync.Mutex
+	jobScheduler *resourceScheduler
+)
+
+// currentScheduler 返回按当前设置创建的调度器；设置改变后新任务使用新的调度器，进行中的任务不受影响
+func currentScheduler() *resourceScheduler {
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+	if jobScheduler == nil || jobScheduler.config != appConfig.SchedulerSettings {
+		jobScheduler = newResourceScheduler(appConfig.SchedulerSettings)
+	}
+	return jobScheduler
+}
+
+// acquire 等待一个空闲槽位，返回释放函数
+func (s *resourceScheduler) acquire(ctx context.Context, kind slotKind) (func(), error) {
+	slots := s.encode
+	if kind == slotGPU {
+		slots = s.gpu
+	}
+	select {
+	case slots <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-slots }) }, nil
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
+
+// run 在槽位中运行一个阶段
+func (s *resourceScheduler) run(ctx context.Context, kind slotKind, stage func() error) error {
+	release, err := s.acquire(ctx, kind)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return stage()
+}
+
+// reserveDisk 从磁盘预算中预留任务的临时空间，预算不足时等待其他任务结束
+// 单个任务超过整个预算时按整个预算预留，即等到没有其他任务时独占运行
+// 需要等待时先调用一次 waiting，供界面显示等待状态
+func (s *resourceScheduler) reserveDisk(ctx context.Context, bytes int64, waiting func()) (func(), error) {
+	if s.diskBudget <= 0 {
+		return func() {}, nil
+	}
+	bytes = min(bytes, s.diskBudget)
+	for notified := false; ; notified = true {
+		s.mu.Lock()
+		if s.diskUsed+bytes <= s.diskBudget {
+			s.diskUsed += bytes
+			s.mu.Unlock()
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					s.mu.Lock()
+					s.diskUsed -= bytes
+					close(s.diskChanged)
+					s.diskChanged = make(chan struct{})
+					s.mu.Unlock()
+				})
+			}, nil
+		}
+		changed := s.diskChanged
+		s.mu.Unlock()
+		if !notified && waiting != nil {
+			waiting()
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+	}
+}
+
+func (s SchedulerSettings) String() string {
+	gpu, encode, disk := max(s.GPUSlots, 0), max(s.EncodeSlots, 0), "不限制"
+	if gpu == 0 {
+		gpu = defaultGPUSlots
+	}
+	if encode == 0 {
+		encode = defaultEncodeSlots
+	}
+	if s.DiskBudgetGB > 0 {
+		disk = fmt.Sprintf("%g GB", s.DiskBudgetGB)
+	}
+	return fmt.Sprintf("插帧槽位 %d  编码槽位 %d  磁盘预算 %s", gpu, encode, disk)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	streamWindowFrames = 100 // 每个 RIFE 窗口处理的帧数，比值的分母较大时加长
+	streamPollInterval = 200 * time.Millisecond
+)
+
+// streamWindowSize 返回按 ratio 插帧时每个窗口的帧数：对齐到比值的分母，
+// 且至少包含 minDenominatorsPerUnit 个分母，使补齐的帧不超过窗口的 1/4
+func streamWindowSize(ratio frameRate) int {
+	return ratio.alignDown(max(streamWindowFrames, minDenominatorsPerUnit*int(ratio.Den)))
+}
+
+// streamWindow 是一个已完成插帧、等待写入编码器的窗口
+type streamWindow struct {
+	dir    string // RIFE 输出目录
+	frames int    // 需要写入编码器的帧数（不含与下一窗口重叠的部分）
+}
+
+// processStreaming 以流水线方式处理视频：
+// ffmpeg 持续拆帧，已就绪的帧按窗口交给 RIFE，插帧结果通过 image2pipe 立即送入编码器，
+// 拆帧、插帧和编码三个阶段同时进行
+func processStreaming(ctx context.Context, job *videoJob) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	inDir := filepath.Join(job.workDir, "in")
+
+	// 拆帧和编码进程同时运行、贯穿整个流水线，两者合占一个编码槽位直到结束；插帧按窗口占用插帧槽位。
+	// 不为拆帧和编码分别申请槽位：默认只有 1 个编码槽位，分别申请会互相等待。
+	// 因此流水线任务运行期间其他任务不能拆帧和编码，编码槽位设为 2 及以上时才能与其他任务交错
+	if job.scheduler != nil {
+		release, err := job.scheduler.acquire(ctx, slotEncode)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	job.ui.Step(stepExtract, StepRunning, "提取视频帧")
+	job.ui.Step(stepInterp, StepRunning, job.stepName())
+	job.ui.Step(stepMerge, StepRunning, "合并视频")
+	job.ui.Progress("流水线处理中...", 40)
+
+	// 1. 后台拆帧
+	extractArgs := append([]string{"-y", "-i", job.inputPath}, job.extractArgs()...)
+	extractor := newCommand(ctx, job.paths.FFmpeg, append(extractArgs, job.format.Pattern(inDir)))
+	if err := startCommand(extractor); err != nil {
+		job.ui.Step(stepExtract, StepError, "提取视频帧")
+		return fmt.Errorf("拆帧失败: %w", err)
+	}
+	extractDone := make(chan error, 1)
+	go func() {
+		extractDone <- extractor.Wait()
+	}()
+
+	// 2. 编码器从标准输入读取帧图片
+	args := []string{
+		"-y",
+		"-f", "image2pipe",
+		"-framerate", job.fpsOrigin.Scale(job.ratio).String(),
+		"-c:v", job.format.PipeCodec(),
+		"-i", "-",
+		"-i", job.audioPath,
+	}
+	if job.needFFMpegInterpolate {
+		args = append(args, "-filter:v", fmt.Sprintf("minterpolate=fps=%s:mi_mode=mci:mc_mode=aobmc:me_mode=bidir_ref:vsbmc=1", job.fpsTarget))
+	}
+	args = append(args,
+		"-c:v", job.codec,
+		"-b:v", "15M",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "copy",
+		"-shortest", job.output.TempPath,
+	)
+
+	encoder := newCommand(ctx, job.paths.FFmpeg, args)
+	stdin, err := encoder.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := startCommand(encoder); err != nil {
+		job.ui.Step(stepMerge, StepError, "合并视频")
+		return fmt.Errorf("启动编码器失败: %w", err)
+	}
+
+	// 3. 插帧在独立的 goroutine 中进行，完成的窗口交给当前 goroutine 写入编码器
+	ready := make(chan streamWindow, 1)
+	go func() {
+		defer close(ready)
+		if err := interpolateWindows(ctx, job, extractDone, ready); err != nil {
+			cancel(err)
+		}
+	}()
+
+	written := 0
+	for window := range ready {
+		if context.Cause(ctx) == nil {
+			n, err := writeWindowFrames(stdin, window, job.format)
+			written += n
+			if err != nil {
+				job.ui.Step(stepMerge, StepError, "合并视频")
+				cancel(fmt.Errorf("写入编码器失败: %w", err))
+			}
+			job.ui.StepProgress(stepMerge, float64(written)/float64(max(job.ratio.scaleCount(job.frames), 1)))
+		}
+		os.RemoveAll(window.dir)
+	}
+
+	stdin.Close()
+	encodeErr := encoder.Wait()
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
+	}
+	if encodeErr != nil {
+		job.ui.Step(stepMerge, StepError, "合并视频")
+		return fmt.Errorf("封装视频失败: %w", commandError(ctx, encodeErr))
+	}
+	if written == 0 {
+		job.ui.Step(stepMerge, StepError, "合并视频")
+		return fmt.Errorf("未能从视频中提取到任何帧")
+	}
+
+	if err := job.output.Commit(); err != nil {
+		job.ui.Step(stepMerge, StepError, "合并视频")
+		return err
+	}
+	job.ui.StepProgress(stepMerge, 1.0)
+	job.ui.Step(stepMerge, StepCompleted, "合并视频")
+
+	return nil
+}
+
+// interpolateWindows 等待拆帧结果凑满一个窗口后调用 RIFE，
+// 每个窗口与下一个窗口重叠 1 帧，保证窗口边界的插值帧不丢失
+func interpolateWindows(ctx context.Context, job *videoJob, extractDone <-chan error, ready chan<- streamWindow) error {
+	inDir := filepath.Join(job.workDir, "in")
+	framePath := func(index int) string {
+		return job.format.FramePath(inDir, index)
+	}
+
+	// 窗口帧数对齐到插帧比值的分母，使各窗口的时间步与整段一次插帧时一致
+	windowFrames := streamWindowSize(job.ratio)
+	extractFinished := false
+	start := 1 // 当前窗口第一帧的编号
+	for window := 0; ; window++ {
+		// 等待窗口内的帧全部写完：第 n 帧在第 n+1 帧出现或拆帧结束后才算完整
+		end := start + windowFrames
+		for !extractFinished && !fileExists(framePath(end+1)) {
+			select {
+			case err := <-extractDone:
+				if err != nil {
+					job.ui.Step(stepExtract, StepError, "提取视频帧")
+					return fmt.Errorf("拆帧失败: %w", commandError(ctx, err))
+				}
+				extractFinished = true
+				job.ui.StepProgress(stepExtract, 1.0)
+				job.ui.Step(stepExtract, StepCompleted, "提取视频帧")
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			case <-time.After(streamPollInterval):
+			}
+		}
+
+		// 拆帧结束后窗口可能不满，找到实际存在的最后一帧
+		final := false
+		if extractFinished {
+			last := start - 1
+			for last < end && fileExists(framePath(last+1)) {
+				last++
+			}
+			final = !fileExists(framePath(last + 1))
+			end = last
+		}
+		count := end - start + 1
+		if count <= 0 {
+			break
+		}
+		if !extractFinished {
+			job.ui.StepProgress(stepExtract, float64(end)/float64(max(job.frames, 1)))
+		}
+
+		// 将窗口内的帧移入独立目录并从 1 开始重新编号，与整段插帧时的输入一致；
+		// 最后一帧还要作为下一个窗口的第一帧，因此只复制
+		windowDir := filepath.Join(job.workDir, fmt.Sprintf("window_%06d", window))
+		windowIn := filepath.Join(windowDir, "in")
+		windowOut := filepath.Join(windowDir, "out")
+		for _, dir := range []string{windowIn, windowOut} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		for index := start; index <= end; index++ {
+			dst := job.format.FramePath(windowIn, index-start+1)
+			var err error
+			if index == end && !final {
+				err = linkOrCopy(framePath(index), dst)
+			} else {
+				err = os.Rename(framePath(index), dst)
+			}
+			if err != nil {
+				return fmt.Errorf("准备插帧窗口失败: %w", err)
+			}
+		}
+
+		if err := job.interpolate(ctx, windowIn, windowOut); err != nil {
+			job.ui.Step(stepInterp, StepError, job.stepName())
+			return fmt.Errorf("%s失败: %w", job.stepName(), err)
+		}
+		os.RemoveAll(windowIn)
+		job.ui.StepProgress(stepInterp, float64(end)/float64(max(job.frames, 1)))
+
+		select {
+		case ready <- streamWindow{dir: windowDir, frames: int(keptFrames(job.ratio, count, final))}:
+		case <-ctx.Done():
+			os.RemoveAll(windowDir)
+			return context.Cause(ctx)
+		}
+
+		if final {
+			break
+		}
+		start = end
+	}
+
+	job.ui.StepProgress(stepInterp, 1.0)
+	job.ui.Step(stepInterp, StepCompleted, job.stepName())
+	return nil
+}
+
+// writeWindowFrames 按顺序将窗口的输出帧写入编码器，返回写入的帧数
+func writeWindowFrames(w io.Writer, window streamWindow, format frameFormat) (int, error) {
+	for i := 1; i <= window.frames; i++ {
+		f, err := os.Open(format.FramePath(filepath.Join(window.dir, "out"), i))
+		if err != nil {
+			return i - 1, err
+		}
+		_, err = io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			return i - 1, err
+		}
+	}
+	return window.frames, nil
+}
+
+// linkOrCopy 优先使用硬链接，不支持时复制文件；dst 已存在时返回错误，不会覆盖
+func linkOrCopy(src, dst string) error {
+	err := os.Link(src, dst)
+	if err == nil || errors.Is(err, fs.ErrExist) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
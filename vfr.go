@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// 可变帧率的处理方式
+const (
+	vfrNormalize  = "cfr"        // 拆帧时按固定帧率重采样（复制或丢弃帧），之后按固定帧率处理
+	vfrTimestamps = "timestamps" // 保留每帧的原始时间，插值帧均匀分布在相邻两帧之间，输出可变帧率视频
+)
+
+const (
+	vfrSamplePackets   = 600  // 检测时读取的数据包数
+	vfrRateTolerance   = 0.01 // r_frame_rate 与 avg_frame_rate 相差超过 1% 视为可变帧率
+	vfrJitterTolerance = 0.10 // 帧间隔偏离中位数超过 10% 视为不规则
+	vfrIrregularShare  = 0.02 // 不规则的帧间隔超过 2% 视为可变帧率
+)
+
+// 统一为固定帧率时优先选择的常见帧率
+var commonFrameRates = []frameRate{
+	{24000, 1001}, {24, 1}, {25, 1}, {30000, 1001}, {30, 1}, {50, 1}, {60000, 1001}, {60, 1},
+}
+
+// vfrAnalysis 是可变帧率检测的结果
+type vfrAnalysis struct {
+	Variable bool
+	Reason   string
+	Nominal  frameRate // 按平均帧率归并到常见帧率后的名义帧率
+}
+
+// detectVFR 比较 r_frame_rate 与 avg_frame_rate，并检查开头一段数据包的时间间隔是否均匀
+func detectVFR(ctx context.Context, ffprobePath, inputPath string, video *StreamInfo) (vfrAnalysis, error) {
+	analysis := vfrAnalysis{Nominal: video.FrameRate}
+	if !video.AvgFrameRate.IsZero() {
+		analysis.Nominal = nearestCommonRate(video.AvgFrameRate)
+		if diff := math.Abs(video.FrameRate.Float()-video.AvgFrameRate.Float()) / video.AvgFrameRate.Float(); diff > vfrRateTolerance {
+			analysis.Variable = true
+			analysis.Reason = fmt.Sprintf("标称帧率 %s 与平均帧率 %s 不一致", video.FrameRate.Label(), video.AvgFrameRate.Label())
+		}
+	}
+
+	times, err := probeFrameTimes(ctx, ffprobePath, inputPath, vfrSamplePackets)
+	if err != nil {
+		return analysis, err
+	}
+	if irregular, total := irregularIntervals(times); total > 0 && float64(irregular)/float64(total) > vfrIrregularShare {
+		reason := fmt.Sprintf("开头 %d 个帧间隔中有 %d 个不均匀", total, irregular)
+		if analysis.Variable {
+			reason = analysis.Reason + "，" + reason
+		}
+		analysis.Variable, analysis.Reason = true, reason
+	}
+	return analysis, nil
+}
+
+// probeFrameTimes 返回视频流数据包的显示时间戳（秒），按时间排序；limit 大于 0 时只读取开头的数据包
+// 只读取数据包，不解码；数据包按解码顺序排列，排序后与拆出的帧一一对应
+func probeFrameTimes(ctx context.Context, ffprobePath, inputPath string, limit int) ([]float64, error) {
+	args := []string{"-v", "error", "-select_streams", "v:0"}
+	if limit > 0 {
+		args = append(args, "-read_intervals", fmt.Sprintf("%%+#%d", limit))
+	}
+	args = append(args, "-show_entries", "packet=pts_time", "-of", "csv=p=0", inputPath)
+
+	output, err := exec.CommandContext(ctx, ffprobePath, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("读取帧时间戳失败: %w", err)
+	}
+
+	var times []float64
+	for _, line := range strings.Split(string(output), "\n") {
+		// 没有时间戳的数据包为 N/A，跳过
+		if pts, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(line), ","), 64); err == nil {
+			times = append(times, pts)
+		}
+	}
+	slices.Sort(times)
+	return times, nil
+}
+
+// irregularIntervals 统计偏离中位数较多的帧间隔个数
+func irregularIntervals(times []float64) (irregular, total int) {
+	var intervals []float64
+	for i := 1; i < len(times); i++ {
+		intervals = append(intervals, times[i]-times[i-1])
+	}
+	if len(intervals) < 2 {
+		return 0, 0
+	}
+	sorted := slices.Clone(intervals)
+	slices.Sort(sorted)
+	median := sorted[len(sorted)/2]
+	if median <= 0 {
+		return 0, 0
+	}
+	for _, interval := range intervals {
+		if math.Abs(interval-median)/median > vfrJitterTolerance {
+			irregular++
+		}
+	}
+	return irregular, len(intervals)
+}
+
+// nearestCommonRate 把平均帧率归并到相差不超过 3% 的常见帧率，否则保留三位小数
+func nearestCommonRate(rate frameRate) frameRate {
+	best, bestDiff := frameRate{}, 0.03
+	for _, common := range commonFrameRates {
+		if diff := math.Abs(common.Float()-rate.Float()) / common.Float(); diff < bestDiff {
+			best, bestDiff = common, diff
+		}
+	}
+	if !best.IsZero() {
+		return best
+	}
+	rounded, err := parseFrameRate(rate.Label())
+	if err != nil {
+		return rate
+	}
+	return rounded
+}
+
+// VFRSettings 是可变帧率视频的处理方式，设置中与其他字段平铺
+type VFRSettings struct {
+	VFRMode string `json:"vfr_mode,omitempty"` // cfr（默认）或 timestamps
+	VFRRate string `json:"vfr_rate,omitempty"` // 统一为固定帧率时使用的帧率，留空按平均帧率自动选择
+}
+
+func (s VFRSettings) validate() error {
+	if s.VFRMode != "" && s.VFRMode != vfrNormalize && s.VFRMode != vfrTimestamps {
+		return fmt.Errorf("可变帧率处理方式只能是 %s 或 %s: %s", vfrNormalize, vfrTimestamps, s.VFRMode)
+	}
+	if !isAutoValue(s.VFRRate) {
+		if _, err := parseFrameRate(s.VFRRate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mode 返回处理方式，留空表示统一为固定帧率
+func (s VFRSettings) mode() string {
+	if s.VFRMode == "" {
+		return vfrNormalize
+	}
+	return s.VFRMode
+}
+
+// handling 返回可变帧率视频的处理方式；统一为固定帧率时使用设置中的帧率，未设置时使用名义帧率
+func (s VFRSettings) handling(analysis vfrAnalysis) *vfrHandling {
+	if s.mode() == vfrTimestamps {
+		return &vfrHandling{Mode: vfrTimestamps, Rate: analysis.Nominal}
+	}
+	return s.normalized(analysis)
+}
+
+// normalized 返回统一为固定帧率的处理方式，不能保留时间戳而改为固定帧率时也使用设置中的帧率
+func (s VFRSettings) normalized(analysis vfrAnalysis) *vfrHandling {
+	h := &vfrHandling{Mode: vfrNormalize, Rate: analysis.Nominal}
+	if rate, err := parseFrameRate(s.VFRRate); err == nil && !isAutoValue(s.VFRRate) {
+		h.Rate = rate
+	}
+	return h
+}
+
+// timestampsUnsupported 返回不能保留原始时间戳的原因，可以保留时返回空字符串
+// 保留时间戳需要完整拆帧后一次封装整数倍插帧的结果：输出固定帧率、流水线和分段处理时都不支持；
+// 分段与否按与 processVideo 相同的方式估算临时空间来判断
+func timestampsUnsupported(mode outputModeOption, nominal frameRate, width, height int, duration float64) string {
+	switch {
+	case !mode.Target.IsZero():
+		return "输出固定帧率时不保留原始时间戳"
+	case appConfig.Pipeline:
+		return "流水线处理不支持保留原始时间戳"
+	}
+	estimate := estimateDiskUsage(width, height, duration, nominal.Float(), nominal.Mul(int64(mode.Multiplier)).Float(), float64(mode.Multiplier), false, appConfig.frameFormat())
+	if limit := appConfig.scratchLimitBytes(); limit > 0 && estimate.ScratchBytes > limit {
+		return "预计临时空间超过上限需要分段处理，分段处理不支持保留原始时间戳"
+	}
+	return ""
+}
+
+// 界面中可变帧率处理方式的显示名称，顺序与 vfrModeValues 对应
+var (
+	vfrModeLabels = []string{"统一为固定帧率", "保留原始时间戳"}
+	vfrModeValues = []string{vfrNormalize, vfrTimestamps}
+)
+
+// vfrHandling 是任务对可变帧率输入采用的处理方式，固定帧率的输入为 nil
+type vfrHandling struct {
+	Mode  string
+	Rate  frameRate // 统一为固定帧率时的帧率；保留时间戳时为名义帧率，只用于估算
+	Times []float64 // 保留时间戳时每一帧的原始时间
+}
+
+// extractArgs 返回拆帧时放在输入之后的参数
+func (j *videoJob) extractArgs() []string {
+	var args []string
+	if j.vfr != nil {
+		switch j.vfr.Mode {
+		case vfrNormalize:
+			args = append(args, "-vf", "fps="+j.vfr.Rate.String())
+		case vfrTimestamps:
+			args = append(args, "-fps_mode", "passthrough")
+		}
+	}
+	return append(args, j.format.EncodeArgs()...)
+}
+
+// writeTimestampConcat 为插帧后的帧写入 ffconcat 列表，每帧的时长按原始时间计算：
+// 原始帧 k 位于 t[k]，其后的插值帧均匀分布在 t[k] 与 t[k+1] 之间
+// 时间戳比原始帧少时（包括最后一帧之后）按平均间隔补齐
+func writeTimestampConcat(listPath, frameDir string, format frameFormat, times []float64, nominal frameRate, multiplier int) error {
+	frames, err := countFiles(frameDir)
+	if err != nil {
+		return err
+	}
+	source := (frames + multiplier - 1) / multiplier
+	step := 1 / nominal.Float()
+	if len(times) >= 2 {
+		step = (times[len(times)-1] - times[0]) / float64(len(times)-1)
+	}
+	for len(times) < source+1 {
+		last := 0.0
+		if len(times) > 0 {
+			last = times[len(times)-1]
+		}
+		times = append(times, last+step)
+	}
+
+	var b strings.Builder
+	b.WriteString("ffconcat version 1.0\n")
+	for i := 1; i <= frames; i++ {
+		k := (i - 1) / multiplier
+		interval := times[k+1] - times[k]
+		if interval <= 0 {
+			interval = step
+		}
+		abs, err := filepath.Abs(format.FramePath(frameDir, i))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "file '%s'\nduration %.6f\n", strings.ReplaceAll(abs, "'", `'\''`), interval/float64(multiplier))
+	}
+	if err := os.WriteFile(listPath, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("写入帧时间列表失败: %w", err)
+	}
+	return nil
+}
+
+func (h *vfrHandling) String() string {
+	if h.Mode == vfrTimestamps {
+		return fmt.Sprintf("保留原始时间戳（%d 帧，名义帧率 %s）", len(h.Times), h.Rate.Label())
+	}
+	return fmt.Sprintf("统一为固定帧率 %s", h.Rate.Label())
+}
+
+// encodeWithTimestamps 按原始时间戳封装插帧结果，输出可变帧率视频；只用于整数倍插帧
+func encodeWithTimestamps(ctx context.Context, job *videoJob, frameDir string) error {
+	listPath := filepath.Join(job.workDir, "frames.ffconcat")
+	if err := writeTimestampConcat(listPath, frameDir, job.format, job.vfr.Times, job.vfr.Rate, int(job.ratio.Num)); err != nil {
+		return err
+	}
+	return runCommand(ctx, job.paths.FFmpeg, []string{
+		"-y",
+		"-f", "concat", "-safe", "0", "-i", listPath,
+		"-i", job.audioPath,
+		"-fps_mode", "vfr",
+		"-c:v", job.codec,
+		"-b:v", "15M",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "copy",
+		"-shortest", job.output.TempPath,
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteTimestampConcat(t *testing.T) {
+	tests := []struct {
+		name       string
+		frames     int
+		times      []float64
+		multiplier int
+		durations  []float64
+	}{
+		{
+			name:       "插值帧均分相邻两帧的间隔",
+			frames:     6,
+			times:      []float64{0, 0.1, 0.3, 0.4},
+			multiplier: 2,
+			durations:  []float64{0.05, 0.05, 0.1, 0.1, 0.05, 0.05},
+		},
+		{
+			name:       "最后一帧之后按平均间隔补齐",
+			frames:     6,
+			times:      []float64{0, 0.2, 0.4},
+			multiplier: 3,
+			durations:  []float64{0.2 / 3, 0.2 / 3, 0.2 / 3, 0.2 / 3, 0.2 / 3, 0.2 / 3},
+		},
+		{
+			name:       "没有时间戳时按名义帧率",
+			frames:     4,
+			multiplier: 2,
+			durations:  []float64{0.02, 0.02, 0.02, 0.02},
+		},
+		{
+			name:       "时间戳不递增时按平均间隔",
+			frames:     4,
+			times:      []float64{0, 0, 0.2},
+			multiplier: 2,
+			durations:  []float64{0.05, 0.05, 0.1, 0.1},
+		},
+	}
+	format := newFrameFormat("png", 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			frameDir := filepath.Join(dir, "out")
+			writeInputFrames(t, format, frameDir, 1, tt.frames)
+			listPath := filepath.Join(dir, "frames.ffconcat")
+
+			if err := writeTimestampConcat(listPath, frameDir, format, tt.times, frameRate{25, 1}, tt.multiplier); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(listPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if lines[0] != "ffconcat version 1.0" {
+				t.Fatalf("第一行为 %q", lines[0])
+			}
+			entries := lines[1:]
+			if len(entries) != 2*len(tt.durations) {
+				t.Fatalf("列表有 %d 行，want %d", len(entries), 2*len(tt.durations))
+			}
+			for i, want := range tt.durations {
+				if file := format.FramePath(frameDir, i+1); entries[2*i] != "file '"+file+"'" {
+					t.Errorf("第 %d 帧为 %s，want %s", i+1, entries[2*i], file)
+				}
+				value, ok := strings.CutPrefix(entries[2*i+1], "duration ")
+				got, err := strconv.ParseFloat(value, 64)
+				if !ok || err != nil || got-want > 1e-6 || want-got > 1e-6 {
+					t.Errorf("第 %d 帧的时长为 %q，want %.6f", i+1, entries[2*i+1], want)
+				}
+			}
+		})
+	}
+}
+
+// writeInputFrames 写入编号为 first..last 的输入帧，内容为全局帧号
+func writeInputFrames(t *testing.T, format frameFormat, dir string, first, last int) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := first; i <= last; i++ {
+		if err := os.WriteFile(format.FramePath(dir, i), []byte(strconv.Itoa(i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
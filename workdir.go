@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	workDirPrefix = "work_"
+	workOwnerFile = ".fps2x-owner" // 记录创建工作目录的进程 PID
+)
+
+type orphanWorkDir struct {
+	Path string
+	Size int64
+}
+
+// getWorkRoot 返回存放 work_* 工作目录的位置
+// 未配置时沿用输出目录，可设置为更快的磁盘或内存盘（如 /dev/shm）
+func getWorkRoot() (string, error) {
+	if appConfig.WorkDir != "" {
+		return appConfig.WorkDir, nil
+	}
+	return getOutputDir()
+}
+
+// createWorkDir 创建带 in/out 子目录的工作目录，并写入所属进程标记
+// 目录名带随机后缀，同名文件同时处理（如界面和命令行各开一个任务）时不会共用目录；
+// 先写标记再建 in 子目录，其他实例清理遗留目录时不会把刚创建的目录当作旧版本遗留
+func createWorkDir(root, baseName string) (string, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", err
+	}
+	workDir, err := os.MkdirTemp(root, workDirPrefix+baseName+"_*")
+	if err != nil {
+		return "", err
+	}
+
+	ownerPath := filepath.Join(workDir, workOwnerFile)
+	if err := os.WriteFile(ownerPath, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		os.RemoveAll(workDir)
+		return "", err
+	}
+
+	for _, sub := range []string{"in", "out"} {
+		if err := os.Mkdir(filepath.Join(workDir, sub), 0755); err != nil {
+			os.RemoveAll(workDir)
+			return "", err
+		}
+	}
+
+	return workDir, nil
+}
+
+// findOrphanWorkDirs 在工作目录位置和输出目录中查找已无进程使用的 work_* 目录
+func findOrphanWorkDirs() ([]orphanWorkDir, error) {
+	var roots []string
+	if workRoot, err := getWorkRoot(); err == nil {
+		roots = append(roots, workRoot)
+	}
+	if outputDir, err := getOutputDir(); err == nil && (len(roots) == 0 || filepath.Clean(outputDir) != filepath.Clean(roots[0])) {
+		roots = append(roots, outputDir)
+	}
+
+	var orphans []orphanWorkDir
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return orphans, err
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() || !strings.HasPrefix(entry.Name(), workDirPrefix) {
+				continue
+			}
+			path := filepath.Join(root, entry.Name())
+			if !isFps2xWorkDir(path) {
+				continue
+			}
+
+			// 旧版本创建的目录没有标记文件，一律视为遗留目录
+			if pid := readPIDFile(filepath.Join(path, workOwnerFile)); pid > 0 && processAlive(pid) {
+				continue
+			}
+			orphans = append(orphans, orphanWorkDir{Path: path, Size: dirSize(path)})
+		}
+	}
+
+	return orphans, nil
+}
+
+// isFps2xWorkDir 避免误删用户自己以 work_ 开头的目录
+func isFps2xWorkDir(path string) bool {
+	if _, err := os.Stat(filepath.Join(path, workOwnerFile)); err == nil {
+		return true
+	}
+	info, err := os.Stat(filepath.Join(path, "in"))
+	return err == nil && info.IsDir()
+}
+
+// removeWorkDirs 删除给定的工作目录，返回释放的字节数
+func removeWorkDirs(dirs []orphanWorkDir) (int64, error) {
+	var freed int64
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir.Path); err != nil {
+			return freed, fmt.Errorf("删除 %s 失败: %w", dir.Path, err)
+		}
+		freed += dir.Size
+	}
+	return freed, nil
+}
+
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
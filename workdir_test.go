@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateWorkDirUnique(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "work")
+
+	// 同一文件名连续创建两次（同一秒内）必须得到不同的目录
+	first, err := createWorkDir(root, "video")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := createWorkDir(root, "video")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("两次创建的工作目录相同: %s", first)
+	}
+
+	for _, dir := range []string{first, second} {
+		if pid := readPIDFile(filepath.Join(dir, workOwnerFile)); pid != os.Getpid() {
+			t.Errorf("%s 的所属进程为 %d，want %d", dir, pid, os.Getpid())
+		}
+		for _, sub := range []string{"in", "out"} {
+			if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+				t.Errorf("%s 缺少 %s 子目录", dir, sub)
+			}
+		}
+		if !isFps2xWorkDir(dir) {
+			t.Errorf("%s 未被识别为工作目录", dir)
+		}
+	}
+	if name := filepath.Base(first); !strings.HasPrefix(name, workDirPrefix+"video_") {
+		t.Errorf("工作目录名 %s 缺少前缀", name)
+	}
+}